@@ -0,0 +1,206 @@
+// Package authz decides if a subject can do an action on an image, a folder or an album. The owner of a resource
+// can do every action, a share link can read the image or folder it shares and the collaborators of a shared album
+// can read it and its images. A denied access returns ErrNotFound so the response does not tell if the resource exists.
+package authz
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DarioRoman01/photos/models"
+)
+
+// ErrNotFound is returned when the subject can not do the action, the resource is reported as not found.
+var ErrNotFound = errors.New("resource not found")
+
+// Action is an operation on a resource.
+type Action string
+
+const (
+	// Read is the action of viewing a resource.
+	Read Action = "read"
+	// Write is the action of changing a resource, like moving an image.
+	Write Action = "write"
+	// Delete is the action of moving a resource to the trash or deleting it.
+	Delete Action = "delete"
+	// Share is the action of sharing a resource with a share link or with collaborators.
+	Share Action = "share"
+	// Contribute is the action of adding images to an album.
+	Contribute Action = "contribute"
+)
+
+// ResourceType is the type of a resource.
+type ResourceType string
+
+const (
+	// ImageResource is the type of the images.
+	ImageResource ResourceType = "image"
+	// FolderResource is the type of the folders.
+	FolderResource ResourceType = "folder"
+	// AlbumResource is the type of the albums.
+	AlbumResource ResourceType = "album"
+)
+
+// Resource is the resource of an access.
+type Resource struct {
+	Type     ResourceType // Type is the type of the resource.
+	ID       string       // ID is the id of the resource.
+	OwnerID  string       // OwnerID is the id of the user who owns the resource.
+	FolderID string       // FolderID is the id of the folder of an image.
+}
+
+// Image returns the resource of an image.
+func Image(image *models.Image) Resource {
+	return Resource{Type: ImageResource, ID: image.ID, OwnerID: image.UserID, FolderID: image.FolderID}
+}
+
+// Folder returns the resource of a folder.
+func Folder(folder *models.Folder) Resource {
+	return Resource{Type: FolderResource, ID: folder.ID, OwnerID: folder.UserID}
+}
+
+// Album returns the resource of an album.
+func Album(album *models.Album) Resource {
+	return Resource{Type: AlbumResource, ID: album.ID, OwnerID: album.UserID}
+}
+
+// Subject is who accesses a resource, an authenticated user or the holder of a share link.
+type Subject struct {
+	UserID    string            // UserID is the id of the authenticated user, empty for a share link.
+	ShareLink *models.ShareLink // ShareLink is the share link of the access, nil for a user.
+}
+
+// User returns the subject of an authenticated user.
+func User(userID string) Subject {
+	return Subject{UserID: userID}
+}
+
+// Link returns the subject of the holder of a share link.
+func Link(link *models.ShareLink) Subject {
+	return Subject{ShareLink: link}
+}
+
+// Role is the role of a collaborator of a shared album.
+type Role string
+
+const (
+	// NoRole is the role of a user who does not collaborate in an album.
+	NoRole Role = ""
+	// Viewer is the role of a collaborator who can view the images of an album.
+	Viewer Role = "viewer"
+	// Contributor is the role of a collaborator who can also add images to an album.
+	Contributor Role = "contributor"
+)
+
+// Relations are the relations between the users and the resources that are not stored in the resources.
+type Relations interface {
+	// CollaboratorRole returns the highest role of the user in the shared albums that contain the image,
+	// NoRole if the user does not collaborate in any of them.
+	CollaboratorRole(userID, imageID string) (Role, error)
+	// AlbumRole returns the role of the user in the album, NoRole if the user does not collaborate in it.
+	AlbumRole(userID, albumID string) (Role, error)
+}
+
+// Authorizer decides the accesses to the resources.
+type Authorizer struct {
+	relations Relations        // relations are the collaborations, nil if there are none.
+	now       func() time.Time // now returns the current time, the share links expire.
+}
+
+// NewAuthorizer returns an authorizer that reads the collaborations from the given relations.
+func NewAuthorizer(relations Relations) *Authorizer {
+	return &Authorizer{relations: relations, now: time.Now}
+}
+
+// Authorize returns nil if the subject can do the action on the resource and ErrNotFound if it can not,
+// any other error is an error reading the relations.
+func (a *Authorizer) Authorize(subject Subject, action Action, resource Resource) error {
+	if subject.ShareLink != nil {
+		if action == Read && a.linkGrants(subject.ShareLink, resource) {
+			return nil
+		}
+
+		return ErrNotFound
+	}
+
+	if subject.UserID == "" {
+		return ErrNotFound
+	}
+
+	if subject.UserID == resource.OwnerID {
+		return nil
+	}
+
+	if (action == Read || action == Contribute) && a.relations != nil {
+		role, err := a.collaboratorRole(subject.UserID, resource)
+		if err != nil {
+			return err
+		}
+
+		if roleGrants(role, action, resource) {
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// roleGrants checks if a collaborator with the role can do the action on the resource, every role can read
+// and the contributors can also add images to the albums.
+func roleGrants(role Role, action Action, resource Resource) bool {
+	switch {
+	case role == NoRole:
+		return false
+	case action == Read:
+		return true
+	default:
+		return action == Contribute && resource.Type == AlbumResource && role == Contributor
+	}
+}
+
+// collaboratorRole returns the role of the user in the shared albums of the resource, NoRole for the folders
+// because they are not shared with collaborators.
+func (a *Authorizer) collaboratorRole(userID string, resource Resource) (Role, error) {
+	switch resource.Type {
+	case ImageResource:
+		return a.relations.CollaboratorRole(userID, resource.ID)
+	case AlbumResource:
+		return a.relations.AlbumRole(userID, resource.ID)
+	default:
+		return NoRole, nil
+	}
+}
+
+// linkGrants checks if an active share link shares the resource, the link of a folder shares its images.
+func (a *Authorizer) linkGrants(link *models.ShareLink, resource Resource) bool {
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && !a.now().Before(*link.ExpiresAt)) {
+		return false
+	}
+
+	if link.UserID != resource.OwnerID {
+		return false
+	}
+
+	switch resource.Type {
+	case ImageResource:
+		return (link.ImageID != nil && *link.ImageID == resource.ID) ||
+			(link.FolderID != nil && *link.FolderID == resource.FolderID)
+	case FolderResource:
+		return link.FolderID != nil && *link.FolderID == resource.ID
+	default:
+		return false
+	}
+}
+
+var authorizer = NewAuthorizer(nil)
+
+// SetRelations sets the relations of the authorizer used by Authorize, the services set the relations
+// of DatabaseRelations once the database is open.
+func SetRelations(relations Relations) {
+	authorizer = NewAuthorizer(relations)
+}
+
+// Authorize decides the access with the authorizer of the service.
+func Authorize(subject Subject, action Action, resource Resource) error {
+	return authorizer.Authorize(subject, action, resource)
+}
@@ -0,0 +1,160 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DarioRoman01/photos/models"
+)
+
+// fakeRelations are the collaborations of the tests by user and image or album.
+type fakeRelations struct {
+	roles map[string]Role
+	err   error
+}
+
+func (r *fakeRelations) CollaboratorRole(userID, imageID string) (Role, error) {
+	return r.roles[userID+"/"+imageID], r.err
+}
+
+func (r *fakeRelations) AlbumRole(userID, albumID string) (Role, error) {
+	return r.roles[userID+"/"+albumID], r.err
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestAuthorize(t *testing.T) {
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	image := Resource{Type: ImageResource, ID: "image", OwnerID: "owner", FolderID: "folder"}
+	otherImage := Resource{Type: ImageResource, ID: "other-image", OwnerID: "owner", FolderID: "other-folder"}
+	folder := Resource{Type: FolderResource, ID: "folder", OwnerID: "owner"}
+	album := Resource{Type: AlbumResource, ID: "album", OwnerID: "owner"}
+	otherAlbum := Resource{Type: AlbumResource, ID: "other-album", OwnerID: "owner"}
+	relations := &fakeRelations{roles: map[string]Role{
+		"viewer/image":      Viewer,
+		"contributor/image": Contributor,
+		"viewer/album":      Viewer,
+		"contributor/album": Contributor,
+		"viewer/folder":     Viewer,
+	}}
+
+	imageLink := &models.ShareLink{UserID: "owner", ImageID: stringPtr("image")}
+	folderLink := &models.ShareLink{UserID: "owner", FolderID: stringPtr("folder")}
+
+	tests := []struct {
+		name      string
+		relations Relations
+		subject   Subject
+		action    Action
+		resource  Resource
+		want      error
+	}{
+		{"owner reads image", relations, User("owner"), Read, image, nil},
+		{"owner writes image", relations, User("owner"), Write, image, nil},
+		{"owner deletes image", relations, User("owner"), Delete, image, nil},
+		{"owner shares image", relations, User("owner"), Share, image, nil},
+		{"owner reads folder", relations, User("owner"), Read, folder, nil},
+		{"stranger reads image", relations, User("stranger"), Read, image, ErrNotFound},
+		{"stranger deletes image", relations, User("stranger"), Delete, image, ErrNotFound},
+		{"stranger reads folder", relations, User("stranger"), Read, folder, ErrNotFound},
+		{"anonymous reads image", relations, Subject{}, Read, image, ErrNotFound},
+		{"viewer reads image", relations, User("viewer"), Read, image, nil},
+		{"viewer writes image", relations, User("viewer"), Write, image, ErrNotFound},
+		{"viewer shares image", relations, User("viewer"), Share, image, ErrNotFound},
+		{"viewer reads other image", relations, User("viewer"), Read, otherImage, ErrNotFound},
+		{"viewer reads folder", relations, User("viewer"), Read, folder, ErrNotFound},
+		{"contributor reads image", relations, User("contributor"), Read, image, nil},
+		{"contributor deletes image", relations, User("contributor"), Delete, image, ErrNotFound},
+		{"collaborator without relations", nil, User("viewer"), Read, image, ErrNotFound},
+		{"owner writes album", relations, User("owner"), Write, album, nil},
+		{"owner shares album", relations, User("owner"), Share, album, nil},
+		{"stranger reads album", relations, User("stranger"), Read, album, ErrNotFound},
+		{"viewer reads album", relations, User("viewer"), Read, album, nil},
+		{"viewer writes album", relations, User("viewer"), Write, album, ErrNotFound},
+		{"viewer shares album", relations, User("viewer"), Share, album, ErrNotFound},
+		{"viewer reads other album", relations, User("viewer"), Read, otherAlbum, ErrNotFound},
+		{"folder is not shared with collaborators", relations, User("viewer"), Read, folder, ErrNotFound},
+		{"owner adds to album", relations, User("owner"), Contribute, album, nil},
+		{"contributor adds to album", relations, User("contributor"), Contribute, album, nil},
+		{"contributor reads album", relations, User("contributor"), Read, album, nil},
+		{"contributor writes album", relations, User("contributor"), Write, album, ErrNotFound},
+		{"viewer adds to album", relations, User("viewer"), Contribute, album, ErrNotFound},
+		{"stranger adds to album", relations, User("stranger"), Contribute, album, ErrNotFound},
+		{"contributor adds to image", relations, User("contributor"), Contribute, image, ErrNotFound},
+		{"link adds to album", relations, Link(folderLink), Contribute, album, ErrNotFound},
+		{"image link reads image", relations, Link(imageLink), Read, image, nil},
+		{"image link reads other image", relations, Link(imageLink), Read, otherImage, ErrNotFound},
+		{"image link reads folder", relations, Link(imageLink), Read, folder, ErrNotFound},
+		{"image link writes image", relations, Link(imageLink), Write, image, ErrNotFound},
+		{"folder link reads folder", relations, Link(folderLink), Read, folder, nil},
+		{"folder link reads image in folder", relations, Link(folderLink), Read, image, nil},
+		{"folder link reads image in other folder", relations, Link(folderLink), Read, otherImage, ErrNotFound},
+		{"folder link deletes image", relations, Link(folderLink), Delete, image, ErrNotFound},
+		{
+			"link of other user",
+			relations,
+			Link(&models.ShareLink{UserID: "stranger", ImageID: stringPtr("image")}),
+			Read, image, ErrNotFound,
+		},
+		{
+			"revoked link",
+			relations,
+			Link(&models.ShareLink{UserID: "owner", ImageID: stringPtr("image"), RevokedAt: timePtr(now.Add(-time.Hour))}),
+			Read, image, ErrNotFound,
+		},
+		{
+			"expired link",
+			relations,
+			Link(&models.ShareLink{UserID: "owner", ImageID: stringPtr("image"), ExpiresAt: timePtr(now)}),
+			Read, image, ErrNotFound,
+		},
+		{
+			"link not expired yet",
+			relations,
+			Link(&models.ShareLink{UserID: "owner", ImageID: stringPtr("image"), ExpiresAt: timePtr(now.Add(time.Minute))}),
+			Read, image, nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthorizer(tt.relations)
+			a.now = func() time.Time { return now }
+			if err := a.Authorize(tt.subject, tt.action, tt.resource); !errors.Is(err, tt.want) {
+				t.Errorf("Authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRelationsError(t *testing.T) {
+	relationsErr := errors.New("connection refused")
+	a := NewAuthorizer(&fakeRelations{err: relationsErr})
+	image := Resource{Type: ImageResource, ID: "image", OwnerID: "owner"}
+
+	tests := []struct {
+		name    string
+		subject Subject
+		action  Action
+		want    error
+	}{
+		{"owner does not read relations", User("owner"), Read, nil},
+		{"stranger read returns the error", User("stranger"), Read, relationsErr},
+		{"stranger write does not read relations", User("stranger"), Write, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.Authorize(tt.subject, tt.action, image); !errors.Is(err, tt.want) {
+				t.Errorf("Authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
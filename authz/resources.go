@@ -0,0 +1,81 @@
+package authz
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/models"
+)
+
+// AuthorizeImage returns the image with the given id if the user can do the action on it,
+// ErrNotFound is returned if the image does not exist or the user can not do the action.
+func AuthorizeImage(userID, id string, action Action) (*models.Image, error) {
+	image, err := database.GetImage(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := Authorize(User(userID), action, Image(image)); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// AuthorizeFolder returns the folder with the given id if the user can do the action on it,
+// ErrNotFound is returned if the folder does not exist or the user can not do the action.
+func AuthorizeFolder(userID, id string, action Action) (*models.Folder, error) {
+	folder, err := database.GetFolder(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := Authorize(User(userID), action, Folder(folder)); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// AuthorizeAlbum returns the album with the given id if the user can do the action on it,
+// ErrNotFound is returned if the album does not exist or the user can not do the action.
+func AuthorizeAlbum(userID, id string, action Action) (*models.Album, error) {
+	album, err := database.GetAlbum(id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if err := Authorize(User(userID), action, Album(album)); err != nil {
+		return nil, err
+	}
+
+	return album, nil
+}
+
+// notFound returns ErrNotFound for the errors of the resources that do not exist.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// databaseRelations reads the collaborations of the shared albums from the database.
+type databaseRelations struct{}
+
+// DatabaseRelations returns the relations stored in the database.
+func DatabaseRelations() Relations {
+	return databaseRelations{}
+}
+
+func (databaseRelations) CollaboratorRole(userID, imageID string) (Role, error) {
+	role, err := database.GetImageRole(userID, imageID)
+	return Role(role), err
+}
+
+func (databaseRelations) AlbumRole(userID, albumID string) (Role, error) {
+	role, err := database.GetAlbumRole(userID, albumID)
+	return Role(role), err
+}
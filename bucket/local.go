@@ -0,0 +1,235 @@
+package bucket
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempFilePrefix is the prefix of the files being written, they are not listed as objects.
+const tempFilePrefix = ".upload-"
+
+// LocalBucketRepository is an implementation of the BucketRepository interface that stores images on the local filesystem.
+type LocalBucketRepository struct {
+	root    string // root is the directory where the objects are stored.
+	baseURL string // baseURL is the URL prefix where the root directory is served.
+}
+
+// NewLocalBucketRepository creates a new LocalBucketRepository that stores the objects under root
+// and builds the objects URLs with the given base URL.
+func NewLocalBucketRepository(root, baseURL string) (*LocalBucketRepository, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local bucket root directory is required")
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	if baseURL == "" {
+		baseURL = "/files"
+	}
+
+	return &LocalBucketRepository{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Prefix returns the URL path where the root directory must be served.
+func (r *LocalBucketRepository) Prefix() string {
+	u, err := url.Parse(r.baseURL)
+	if err != nil || u.Path == "" {
+		return "/"
+	}
+
+	return u.Path
+}
+
+// path returns the filesystem path for the given key, ErrInvalidKey is returned if the key has ".." segments.
+func (r *LocalBucketRepository) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(r.root, filepath.FromSlash(filepath.Clean("/"+key))), nil
+}
+
+// Open opens the object with the given key for reading.
+func (r *LocalBucketRepository) Open(key string) (*os.File, error) {
+	path, err := r.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// objectURL returns the public URL of the given key.
+func (r *LocalBucketRepository) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s", r.baseURL, key)
+}
+
+// Delete deletes an image from the bucket.
+func (r *LocalBucketRepository) Delete(key string) error {
+	path, err := r.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// write writes the object with the given key to a temporary file that is renamed once it is complete.
+func (r *LocalBucketRepository) write(key string, file io.Reader) error {
+	path, err := r.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), tempFilePrefix+"*")
+	if err != nil {
+		log.Printf("Error creating file: %s", err)
+		return err
+	}
+
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, file); err != nil {
+		f.Close()
+		log.Printf("Error writing file: %s", err)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Upload uploads an image to the bucket and returns the URL of the image, the file is written
+// to a temporary file that is renamed once it is complete.
+func (r *LocalBucketRepository) Upload(file io.Reader, fileName, username, folder string) (string, error) {
+	key := objectKey(username, folder, fileName)
+	if err := r.write(key, file); err != nil {
+		return "", err
+	}
+
+	return r.objectURL(key), nil
+}
+
+// MoveFile moves a file from one folder to another and returns the new file's URL.
+func (r *LocalBucketRepository) MoveFile(username, oldPath, newPath, filename string) (string, error) {
+	key := objectKey(username, newPath, filename)
+	dst, err := r.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := r.path(objectKey(username, oldPath, filename))
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		log.Printf("Error moving file: %s", err)
+		return "", err
+	}
+
+	return r.objectURL(key), nil
+}
+
+// List returns the keys of the objects whose key starts with the prefix.
+func (r *LocalBucketRepository) List(prefix string) ([]string, error) {
+	dir, err := r.path(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(prefix, "/") {
+		dir = filepath.Dir(dir)
+	}
+
+	keys := []string{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempFilePrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.root, path)
+		if err != nil {
+			return err
+		}
+
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+// DeletePrefix deletes every object whose key starts with the prefix and returns the number of deleted objects.
+func (r *LocalBucketRepository) DeletePrefix(prefix string) (int, error) {
+	keys, err := r.List(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, key := range keys {
+		if err := r.Delete(key); err != nil {
+			return i, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// Copy copies the object with the key src to the key dst, replacing the object of dst if it exists.
+func (r *LocalBucketRepository) Copy(src, dst string) error {
+	f, err := r.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	return r.write(dst, f)
+}
+
+// URL returns the public URL of the object with the given key.
+func (r *LocalBucketRepository) URL(key string) string {
+	return r.objectURL(key)
+}
@@ -1,21 +1,96 @@
 package bucket
 
 import (
-	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 )
 
+// ErrInvalidKey is returned when an object key has ".." segments.
+var ErrInvalidKey = errors.New("invalid object key")
+
 // BucketRepository is an interface for a repository that stores and retrieves images.
 type BucketRepository interface {
 	// Delete deletes an image from the bucket.
 	Delete(key string) error
-	// Upload uploads an image to the bucket.
-	Upload(file *bytes.Buffer, fileName, username, folder string) (string, error)
+	// Upload uploads an image of unknown length to the bucket reading it until EOF.
+	Upload(file io.Reader, fileName, username, folder string) (string, error)
 	// MoveFile copy a file from one folder to another and deletes the original file and returns the new file's URL.
 	MoveFile(username, oldPath, newPath, filename string) (string, error)
+	// List returns the keys of the objects whose key starts with the prefix.
+	List(prefix string) ([]string, error)
+	// DeletePrefix deletes every object whose key starts with the prefix and returns the number of deleted objects.
+	DeletePrefix(prefix string) (int, error)
+	// Copy copies the object with the key src to the key dst, replacing the object of dst if it exists.
+	Copy(src, dst string) error
+	// URL returns the public URL of the object with the given key.
+	URL(key string) string
 }
 
 var bucketRepository BucketRepository
 
+// NewBucketRepository creates the bucket repository selected by the BUCKET_DRIVER env variable,
+// "s3" is used when the driver is not set.
+func NewBucketRepository() (BucketRepository, error) {
+	switch driver := os.Getenv("BUCKET_DRIVER"); driver {
+	case "", "s3":
+		return NewS3BucketRepository()
+	case "local":
+		return NewLocalBucketRepository(os.Getenv("LOCAL_BUCKET_ROOT"), os.Getenv("LOCAL_BUCKET_URL"))
+	default:
+		return nil, fmt.Errorf("unknown bucket driver %q", driver)
+	}
+}
+
+// ServedFiles returns the URL path where the query service must serve the stored files,
+// ok is false when the configured repository serves its files by itself.
+func ServedFiles() (prefix string, ok bool) {
+	local, ok := bucketRepository.(*LocalBucketRepository)
+	if !ok {
+		return "", false
+	}
+
+	return local.Prefix(), true
+}
+
+// OpenServedFile opens a file served by the query service, ErrInvalidKey is returned if the
+// configured repository serves its files by itself.
+func OpenServedFile(key string) (*os.File, error) {
+	local, ok := bucketRepository.(*LocalBucketRepository)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	return local.Open(key)
+}
+
+// ValidKey reports whether the key can be used, the keys can not have ".." segments so a key built
+// from a request can not point outside the objects of its user.
+func ValidKey(key string) bool {
+	if strings.Contains(key, "\\") {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+// objectKey returns the key where the given file is stored.
+func objectKey(username, folder, fileName string) string {
+	if folder == "" {
+		folder = "default"
+	}
+
+	return fmt.Sprintf("%s/%s/%s", username, folder, fileName)
+}
+
 func SetBucketRepository(repository BucketRepository) {
 	bucketRepository = repository
 }
@@ -24,10 +99,26 @@ func Delete(key string) error {
 	return bucketRepository.Delete(key)
 }
 
-func Upload(file *bytes.Buffer, fileName, username, folder string) (string, error) {
+func Upload(file io.Reader, fileName, username, folder string) (string, error) {
 	return bucketRepository.Upload(file, fileName, username, folder)
 }
 
 func MoveFile(username, oldPath, newPath, filename string) (string, error) {
 	return bucketRepository.MoveFile(username, oldPath, newPath, filename)
 }
+
+func List(prefix string) ([]string, error) {
+	return bucketRepository.List(prefix)
+}
+
+func DeletePrefix(prefix string) (int, error) {
+	return bucketRepository.DeletePrefix(prefix)
+}
+
+func Copy(src, dst string) error {
+	return bucketRepository.Copy(src, dst)
+}
+
+func URL(key string) string {
+	return bucketRepository.URL(key)
+}
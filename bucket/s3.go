@@ -1,9 +1,10 @@
 package bucket
 
 import (
-	"bytes"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -13,6 +14,14 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
+const (
+	// uploadPartSize is the size of the parts of the multipart uploads, it is the minimum allowed by s3.
+	uploadPartSize = s3manager.MinUploadPartSize
+	// uploadConcurrency is the number of parts uploaded in parallel for each file, every
+	// upload buffers at most uploadConcurrency+1 parts in memory.
+	uploadConcurrency = 2
+)
+
 // S3BucketRepository is an implementation of the BucketRepository interface that stores and retrieves images in an S3 bucket.
 type S3BucketRepository struct {
 	client *session.Session // client is the AWS SDK client.
@@ -43,21 +52,17 @@ func (r *S3BucketRepository) Delete(key string) error {
 	return err
 }
 
-// Upload uploads an image to the bucket and returns the URL of the image.
-func (repository *S3BucketRepository) Upload(file *bytes.Buffer, fileName, username, folder string) (string, error) {
-	uploader := s3manager.NewUploader(repository.client)
-
-	// verify if the path is not empty
-	var path string
-	if folder == "" {
-		path = fmt.Sprintf("%s/default/%s", username, fileName)
-	} else {
-		path = fmt.Sprintf("%s/%s/%s", username, folder, fileName)
-	}
+// Upload uploads an image to the bucket and returns the URL of the image, the file is sent
+// in a multipart upload so it is never fully buffered in memory.
+func (repository *S3BucketRepository) Upload(file io.Reader, fileName, username, folder string) (string, error) {
+	uploader := s3manager.NewUploader(repository.client, func(u *s3manager.Uploader) {
+		u.PartSize = uploadPartSize
+		u.Concurrency = uploadConcurrency
+	})
 
 	r, err := uploader.Upload(&s3manager.UploadInput{
 		Bucket:      aws.String(os.Getenv("S3_BUCKET")),
-		Key:         aws.String(path),
+		Key:         aws.String(objectKey(username, folder, fileName)),
 		Body:        file,
 		ContentType: aws.String("image/jpeg"),
 	})
@@ -72,28 +77,108 @@ func (repository *S3BucketRepository) Upload(file *bytes.Buffer, fileName, usern
 
 // MoveFile copy a file from one folder to another and deletes the original file and returns the new file's URL.
 func (r *S3BucketRepository) MoveFile(username, oldPath, newPath, filename string) (string, error) {
+	src, dst := objectKey(username, oldPath, filename), objectKey(username, newPath, filename)
+	if err := r.Copy(src, dst); err != nil {
+		log.Printf("Error copying file: %s", err)
+		return "", err
+	}
+
+	if err := r.Delete(src); err != nil {
+		log.Printf("Error deleting file: %s", err)
+		return "", err
+	}
+
+	return r.URL(dst), nil
+}
+
+// List returns the keys of the objects whose key starts with the prefix.
+func (r *S3BucketRepository) List(prefix string) ([]string, error) {
+	svc := s3.New(r.client)
+	keys := []string{}
+	err := svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(os.Getenv("S3_BUCKET")),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+
+		return true
+	})
+
+	return keys, err
+}
+
+// DeletePrefix deletes every object whose key starts with the prefix and returns the number of deleted objects,
+// the objects of every listed page are deleted in a single request.
+func (r *S3BucketRepository) DeletePrefix(prefix string) (int, error) {
 	bucketName := os.Getenv("S3_BUCKET")
 	svc := s3.New(r.client)
-	opt, err := svc.CopyObject(&s3.CopyObjectInput{
-		Bucket:     aws.String(bucketName),
-		CopySource: aws.String(fmt.Sprintf("%s/%s/%s/%s", bucketName, username, oldPath, filename)),
-		Key:        aws.String(fmt.Sprintf("%s/%s/%s", username, newPath, filename)),
+	deleted := 0
+	var deleteErr error
+	err := svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, last bool) bool {
+		if len(page.Contents) == 0 {
+			return true
+		}
+
+		objects := make([]*s3.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+		}
+
+		out, err := svc.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+
+		if err != nil {
+			deleteErr = err
+			return false
+		}
+
+		deleted += len(objects) - len(out.Errors)
+		if len(out.Errors) > 0 {
+			deleteErr = fmt.Errorf("error deleting %s: %s", aws.StringValue(out.Errors[0].Key), aws.StringValue(out.Errors[0].Message))
+			return false
+		}
+
+		return true
 	})
 
 	if err != nil {
-		log.Printf("Error copying file: %s", err)
-		return "", err
+		return deleted, err
 	}
 
-	_, err = svc.DeleteObject(&s3.DeleteObjectInput{
+	return deleted, deleteErr
+}
+
+// Copy copies the object with the key src to the key dst, replacing the object of dst if it exists.
+func (r *S3BucketRepository) Copy(src, dst string) error {
+	bucketName := os.Getenv("S3_BUCKET")
+	svc := s3.New(r.client)
+	_, err := svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(bucketName),
+		CopySource: aws.String((&url.URL{Path: bucketName + "/" + src}).EscapedPath()),
+		Key:        aws.String(dst),
+	})
+
+	return err
+}
+
+// URL returns the public URL of the object with the given key, it is built like the URLs of the uploads.
+func (r *S3BucketRepository) URL(key string) string {
+	req, _ := s3.New(r.client).GetObjectRequest(&s3.GetObjectInput{
 		Bucket: aws.String(os.Getenv("S3_BUCKET")),
-		Key:    aws.String(fmt.Sprintf("%s/%s/%s", username, oldPath, filename)),
+		Key:    aws.String(key),
 	})
 
-	if err != nil {
-		log.Printf("Error deleting file: %s", err)
-		return "", err
+	if err := req.Build(); err != nil {
+		log.Printf("Error building object url: %s", err)
+		return ""
 	}
 
-	return opt.CopyObjectResult.GoString(), nil
+	return req.HTTPRequest.URL.String()
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxAlbumNameLength is the maximum length of the name of an album.
+	maxAlbumNameLength = 255
+	// maxAlbumImages is the maximum number of images added to an album by a request.
+	maxAlbumImages = 1000
+)
+
+// validAlbumName reports whether the name can be used as an album name, unlike the folder names
+// the album names are not paths so they can contain slashes.
+func validAlbumName(name string) bool {
+	return name != "" && len(name) <= maxAlbumNameLength && strings.TrimSpace(name) == name
+}
+
+// CreateAlbumHandler creates an empty album for the user.
+func (s *CommandService) CreateAlbumHandler(c *fiber.Ctx) error {
+	var req models.Album
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	if !validAlbumName(req.Name) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid album name"))
+	}
+
+	album := &models.Album{ID: uuid.NewString(), Name: req.Name, UserID: middlewares.UserID(c)}
+	if err := database.InsertAlbum(album); err != nil {
+		log.Printf("Error inserting album: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating album"))
+	}
+
+	album, err := database.GetAlbum(album.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting album"))
+	}
+
+	return c.Status(http.StatusCreated).JSON(album)
+}
+
+// UpdateAlbumHandler renames an album or changes its cover, the cover must be an image of the album.
+func (s *CommandService) UpdateAlbumHandler(c *fiber.Ctx) error {
+	req := new(models.UpdateAlbumRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid request"))
+	}
+
+	if req.Name != "" && !validAlbumName(req.Name) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid album name"))
+	}
+
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Write)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error updating album")
+	}
+
+	if req.Name != "" {
+		album.Name = req.Name
+	}
+
+	if req.CoverImageID != nil {
+		album.CoverImageID = nil
+		if *req.CoverImageID != "" {
+			album.CoverImageID = req.CoverImageID
+		}
+	}
+
+	if err := database.UpdateAlbum(album); err != nil {
+		if errors.Is(err, database.ErrNotInAlbum) {
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("The cover must be an image of the album"))
+		}
+
+		return notFoundError(c, err, "Album not found", "Error updating album")
+	}
+
+	album, err = database.GetAlbum(album.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting album"))
+	}
+
+	return c.Status(http.StatusOK).JSON(album)
+}
+
+// DeleteAlbumHandler deletes an album, its images stay in their folders.
+func (s *CommandService) DeleteAlbumHandler(c *fiber.Ctx) error {
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Delete)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error deleting album")
+	}
+
+	if err := database.DeleteAlbum(album.ID, album.UserID); err != nil {
+		return notFoundError(c, err, "Album not found", "Error deleting album")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Album deleted"})
+}
+
+// parseAlbumImages parses the image ids of a request to change the images of an album.
+func parseAlbumImages(c *fiber.Ctx) ([]string, error) {
+	req := new(models.AlbumImagesRequest)
+	if err := c.BodyParser(req); err != nil {
+		return nil, errors.New("Invalid request")
+	}
+
+	if len(req.ImageIDs) > maxAlbumImages {
+		return nil, errors.New("Too many images")
+	}
+
+	for _, id := range req.ImageIDs {
+		if id == "" {
+			return nil, errors.New("Invalid image id")
+		}
+	}
+
+	return req.ImageIDs, nil
+}
+
+// AddAlbumImagesHandler adds images of the user to the end of an album of the user or of an album the user
+// contributes to, the images already in the album are skipped. The images are only referenced by the album
+// so no object is copied.
+func (s *CommandService) AddAlbumImagesHandler(c *fiber.Ctx) error {
+	imageIDs, err := parseAlbumImages(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError(err.Error()))
+	}
+
+	if len(imageIDs) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("image_ids is required"))
+	}
+
+	userID := middlewares.UserID(c)
+	album, err := authz.AuthorizeAlbum(userID, c.Params("albumID"), authz.Contribute)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error adding images")
+	}
+
+	added, err := database.AddAlbumImages(album.ID, userID, imageIDs)
+	if err != nil {
+		return notFoundError(c, err, "Image not found", "Error adding images")
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{"added": added})
+}
+
+// ReorderAlbumImagesHandler sets the order of the images of an album, the request must contain every image of
+// the album that is not in the trash.
+func (s *CommandService) ReorderAlbumImagesHandler(c *fiber.Ctx) error {
+	imageIDs, err := parseAlbumImages(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError(err.Error()))
+	}
+
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Write)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error reordering images")
+	}
+
+	if err := database.ReorderAlbumImages(album.ID, imageIDs); err != nil {
+		if errors.Is(err, database.ErrInvalidAlbumOrder) {
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("The order must contain every image of the album once"))
+		}
+
+		return notFoundError(c, err, "Album not found", "Error reordering images")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Album reordered"})
+}
+
+// RemoveAlbumImageHandler removes an image from an album, the image is not deleted.
+func (s *CommandService) RemoveAlbumImageHandler(c *fiber.Ctx) error {
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Write)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error removing image")
+	}
+
+	if err := database.RemoveAlbumImage(album.ID, c.Params("imageID")); err != nil {
+		return notFoundError(c, err, "Image not found", "Error removing image")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Image removed from album"})
+}
+
+// albumCollaborator returns the user of the username param of a request to change the collaborators of an album,
+// database.ErrNotFound is returned if there is no user with the username.
+func albumCollaborator(c *fiber.Ctx) (*models.User, error) {
+	username, err := url.PathUnescape(c.Params("username"))
+	if err != nil {
+		return nil, database.ErrNotFound
+	}
+
+	user, err := database.GetUserByUsername(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, database.ErrNotFound
+	}
+
+	return user, err
+}
+
+// SetAlbumCollaboratorHandler shares an album with a user as a viewer or a contributor, or changes the role of a
+// collaborator. The collaborators can read the album and its images.
+func (s *CommandService) SetAlbumCollaboratorHandler(c *fiber.Ctx) error {
+	req := new(models.AlbumCollaboratorRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid request"))
+	}
+
+	if role := authz.Role(req.Role); role != authz.Viewer && role != authz.Contributor {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid role"))
+	}
+
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Share)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error sharing album")
+	}
+
+	user, err := albumCollaborator(c)
+	if err != nil {
+		return notFoundError(c, err, "User not found", "Error sharing album")
+	}
+
+	if user.ID == album.UserID {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("The owner of the album can not be a collaborator"))
+	}
+
+	if err := database.SetAlbumCollaborator(album.ID, user.ID, req.Role); err != nil {
+		log.Printf("Error setting album collaborator: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error sharing album"))
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Collaborator saved"})
+}
+
+// RemoveAlbumCollaboratorHandler stops sharing an album with a user.
+func (s *CommandService) RemoveAlbumCollaboratorHandler(c *fiber.Ctx) error {
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Share)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error removing collaborator")
+	}
+
+	user, err := albumCollaborator(c)
+	if err != nil {
+		return notFoundError(c, err, "Collaborator not found", "Error removing collaborator")
+	}
+
+	if err := database.RemoveAlbumCollaborator(album.ID, user.ID); err != nil {
+		return notFoundError(c, err, "Collaborator not found", "Error removing collaborator")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Collaborator removed"})
+}
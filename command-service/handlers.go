@@ -1,33 +1,54 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"time"
+	"path/filepath"
+	"strings"
 
+	"github.com/DarioRoman01/photos/authz"
 	"github.com/DarioRoman01/photos/bucket"
 	"github.com/DarioRoman01/photos/database"
-	"github.com/DarioRoman01/photos/mailpb"
+	"github.com/DarioRoman01/photos/middlewares"
 	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/oidc"
+	"github.com/DarioRoman01/photos/ratelimit"
 	"github.com/DarioRoman01/photos/uploadpb"
 	"github.com/DarioRoman01/photos/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // CommandService is the service that handles the commands, commands are the write operations
 type CommandService struct {
-	mailService   mailpb.MailServiceClient     // mailService is the mail service client
 	uploadService uploadpb.UploadServiceClient // uploadService is the upload service
+	uploads       *uploadStore                 // uploads stores the in progress resumable uploads
+	tus           *tusConfig                   // tus is the resumable uploads configuration
+	trash         *trashConfig                 // trash is the trash configuration
+	logins        *throttle                    // logins throttles the failed logins
+	resends       *throttle                    // resends throttles the verification mails sent again
+	oidc          *oidc.Provider               // oidc is the external identity provider, nil if it is not configured
 }
 
 // NewCommandService creates a new command service
 func NewCommandService() (*CommandService, error) {
+	if err := utils.LoadKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := utils.CheckPasswordResetSecret(); err != nil {
+		return nil, err
+	}
+
 	// create the database repository
 	db, err := database.NewPostgresRepository(os.Getenv("POSTGRES_URL"))
 	if err != nil {
@@ -35,28 +56,74 @@ func NewCommandService() (*CommandService, error) {
 	}
 
 	// create the bucket repository
-	s3Bucket, err := bucket.NewS3BucketRepository()
+	bucketRepo, err := bucket.NewBucketRepository()
 	if err != nil {
 		return nil, err
 	}
 
-	// create the mail service client
-	mailConn, err := grpc.Dial("mailService:5060", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	uploadConn, err := grpc.Dial("uploadService:5070", grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, err
 	}
 
-	uploadConn, err := grpc.Dial("uploadService:5070", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	tus, err := newTusConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	uploadDir := os.Getenv("TUS_UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = filepath.Join(os.TempDir(), "photos-uploads")
+	}
+
+	uploads, err := newUploadStore(uploadDir)
+	if err != nil {
+		return nil, err
+	}
+
+	go uploads.removeExpired(tus.expiration)
+
+	trash, err := newTrashConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	mailService := mailpb.NewMailServiceClient(mailConn)
 	uploadService := uploadpb.NewUploadServiceClient(uploadConn)
 	database.SetDatabaseRepository(db)
-	bucket.SetBucketRepository(s3Bucket)
+	bucket.SetBucketRepository(bucketRepo)
+	authz.SetRelations(authz.DatabaseRelations())
+
+	store, err := ratelimit.NewStore()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := newOIDCProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &CommandService{
+		uploadService: uploadService,
+		uploads:       uploads,
+		tus:           tus,
+		trash:         trash,
+		logins:        newThrottle(store, "login", ratelimit.IPPolicy, ratelimit.AccountPolicy),
+		resends:       newThrottle(store, "resend", ratelimit.IPPolicy, ratelimit.ResendPolicy),
+		oidc:          provider,
+	}
+
+	go s.purgeExpired()
+	go recoverRelocations()
+	return s, nil
+}
 
-	return &CommandService{mailService, uploadService}, nil
+// mailLocale returns the preferred locale of the request from its Accept-Language header,
+// the mail service falls back to its default locale when it has no templates for it.
+func mailLocale(c *fiber.Ctx) string {
+	locale, _, _ := strings.Cut(c.Get(fiber.HeaderAcceptLanguage), ",")
+	locale, _, _ = strings.Cut(locale, ";")
+	return strings.TrimSpace(locale)
 }
 
 // RegisterHandler handles the registration of a new user request
@@ -66,6 +133,20 @@ func (s *CommandService) RegisterHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
 	}
 
+	// the username is the first segment of the keys of the objects of the user, so it must be a
+	// valid segment and must not be used by other user
+	if !utils.ValidUsername(input.Username) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid username"))
+	}
+
+	if _, err := database.GetUserByUsername(input.Username); !errors.Is(err, sql.ErrNoRows) {
+		if err == nil {
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("Username already used"))
+		}
+
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
 	hashPwd, err := utils.GeneratePassword(utils.GetDefaultPasswordConfig(), input.Password)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error generating password"))
@@ -79,28 +160,24 @@ func (s *CommandService) RegisterHandler(c *fiber.Ctx) error {
 		Password: input.Password,
 	}
 
-	if err := database.InsertUser(user); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating user"))
-	}
-
 	token, err := utils.CreateToken(user.Username, user.ID, models.VerifyToken.String())
 	if err != nil {
 		log.Printf("Error creating token: %v", err)
 		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
 	}
 
-	_, err = s.mailService.SendMail(c.Context(), &mailpb.SendMailRequest{
+	// the verification mail is sent by the mail service from the outbox
+	mail := &models.OutboxMail{
+		ID:       uuid.NewString(),
 		Type:     models.VerifyToken.String(),
-		Receiver: input.Email,
-		Subject:  "Verify your email",
-		Body:     "Please verify your email",
-		User:     user.Username,
+		Receiver: user.Email,
+		Username: user.Username,
 		Token:    token,
-	})
+		Locale:   mailLocale(c),
+	}
 
-	if err != nil {
-		log.Printf("token: %v", token)
-		log.Printf("Error sending email: %v", err)
+	if err := database.InsertUserWithMail(user, mail); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating user"))
 	}
 
 	return c.Status(http.StatusCreated).JSON(user)
@@ -122,6 +199,20 @@ func (s *CommandService) HandleVerify(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid token"))
 	}
 
+	user, err := database.GetUserByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid token"))
+		}
+
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
+	// the links can be opened more than once, a verified user gets a success response
+	if user.IsVerified {
+		return c.Status(http.StatusOK).JSON(map[string]string{"message": "User already verified"})
+	}
+
 	if err := database.UpdateUserStatus(claims.UserID); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error updating user"))
 	}
@@ -135,68 +226,117 @@ func (s *CommandService) LoginHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
 	}
 
-	user, err := database.GetUserByEmail(input.Email)
+	account := accountKey(input.Email)
+	wait, err := s.logins.attempt(c.IP(), account)
 	if err != nil {
+		log.Printf("Error counting login attempt: %v", err)
 		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
 	}
 
-	if user == nil {
-		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("User not found"))
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
 	}
 
-	ok, _ := utils.ComparePasswords(input.Password, user.Password)
+	user, err := database.GetUserByEmail(input.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
+	// the same response is returned for unknown emails and wrong passwords
+	hash := dummyPasswordHash()
+	if user != nil {
+		hash = user.Password
+	}
 
-	if !ok {
-		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid password"))
+	// the attempt was counted before the password is compared, so a failed login has nothing left to record
+	if ok, _ := utils.ComparePasswords(input.Password, hash); !ok || user == nil {
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Invalid email or password"))
 	}
 
-	token, err := utils.CreateToken(user.Username, user.ID, models.AccessToken.String())
+	// the failures of an account with two factor authentication are reset after the code is checked
+	if user.TOTPEnabled {
+		if err := s.logins.release(c.IP(), account); err != nil {
+			log.Printf("Error releasing login attempt: %v", err)
+		}
+
+		token, err := utils.CreateTwoFactorToken(user.Username, user.ID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+		}
+
+		return c.Status(http.StatusOK).JSON(fiber.Map{"two_factor_required": true, "token": token})
+	}
+
+	if err := s.logins.succeed(c.IP(), account); err != nil {
+		log.Printf("Error resetting login attempts: %v", err)
+	}
+
+	token, refreshToken, err := createSession(c, input.Device, user)
 	if err != nil {
+		log.Printf("Error creating session: %v", err)
 		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name:    "token",
-		Value:   token,
-		Expires: time.Now().Add(time.Hour * 24 * 7),
-	})
-
-	return c.Status(http.StatusOK).JSON(map[string]string{"token": token})
+	setAuthCookies(c, token, refreshToken)
+	return c.Status(http.StatusOK).JSON(map[string]string{"token": token, "refresh_token": refreshToken})
 }
 
+// LogoutHandler revokes the current session and clears the auth cookies.
 func (s *CommandService) LogoutHandler(c *fiber.Ctx) error {
-	c.Cookie(&fiber.Cookie{
-		Name:    "token",
-		Value:   "",
-		Expires: time.Now().Add(-time.Hour),
-	})
+	userID := middlewares.UserID(c)
+	if sessionID, ok := c.Locals("session_id").(string); ok {
+		if err := database.RevokeSession(sessionID, userID); err != nil && !errors.Is(err, database.ErrNotFound) {
+			log.Printf("Error revoking session: %v", err)
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error logging out"))
+		}
+	}
 
+	clearAuthCookies(c)
 	return c.Status(http.StatusOK).JSON(map[string]string{"message": "User logged out"})
 }
 
+// CreateFolderHandler creates a folder in the root or, if parent_id is set, in another folder of the user.
 func (s *CommandService) CreateFolderHandler(c *fiber.Ctx) error {
 	var folder models.Folder
 	if err := c.BodyParser(&folder); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
 	}
 
+	if !utils.ValidFolderName(folder.Name) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid folder name"))
+	}
+
+	if folder.ParentID != nil && *folder.ParentID == "" {
+		folder.ParentID = nil
+	}
+
+	if folder.ParentID != nil {
+		if _, err := authz.AuthorizeFolder(middlewares.UserID(c), *folder.ParentID, authz.Write); err != nil {
+			return notFoundError(c, err, "Folder not found", "Error creating folder")
+		}
+	}
+
 	folder.ID = uuid.NewString()
-	folder.UserID = c.Locals("user_id").(string)
+	folder.UserID = middlewares.UserID(c)
 	if err := database.InsertFolder(&folder); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating folder"))
+		if errors.Is(err, database.ErrFolderExists) {
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("Folder already exists"))
+		}
+
+		return notFoundError(c, err, "Folder not found", "Error creating folder")
 	}
 
 	return c.Status(http.StatusCreated).JSON(folder)
 }
 
 func (s *CommandService) getUploadData(c *fiber.Ctx) (*models.UploadRequest, error) {
-	folder := c.Query("path")
-	if folder == "" {
-		folder = "default"
+	folder, err := utils.CleanFolderPath(c.Query("path"))
+	if err != nil {
+		return nil, err
 	}
 
-	userId := c.Locals("user_id").(string)
-	username := c.Locals("username").(string)
+	userId := middlewares.UserID(c)
+	username := middlewares.Username(c)
 	folderId, err := database.CheckFolder(userId, folder)
 	if err != nil {
 		return nil, err
@@ -207,6 +347,10 @@ func (s *CommandService) getUploadData(c *fiber.Ctx) (*models.UploadRequest, err
 		return nil, err
 	}
 
+	if !utils.ValidFilename(fileHeader.Filename) {
+		return nil, utils.ErrInvalidFilename
+	}
+
 	file, err := fileHeader.Open()
 	if err != nil {
 		return nil, err
@@ -222,11 +366,11 @@ func (s *CommandService) getUploadData(c *fiber.Ctx) (*models.UploadRequest, err
 	}, nil
 }
 
-// streamData streams the data to the upload service
-func (s *CommandService) streamData(c *fiber.Ctx, req *models.UploadRequest) (string, error) {
-	stream, err := s.uploadService.Upload(c.Context())
+// streamData streams the data to the upload service, the file is stored with the given object name.
+func (s *CommandService) streamData(ctx context.Context, req *models.UploadRequest, objectName string) (*uploadpb.UploadResponse, error) {
+	stream, err := s.uploadService.Upload(ctx)
 	if err != nil {
-		return "", c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error uploading file"))
+		return nil, err
 	}
 
 	buf := make([]byte, 1024)
@@ -237,118 +381,230 @@ func (s *CommandService) streamData(c *fiber.Ctx, req *models.UploadRequest) (st
 				break
 			}
 
-			return "", c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error reading file"))
+			return nil, err
 		}
 
 		err = stream.Send(&uploadpb.UploadRequest{
 			Username: req.Username,
 			Folder:   req.FolderName,
-			Filename: req.Filename,
+			Filename: objectName,
 			Chunk:    buf[:n],
 		})
 
 		if err != nil {
-			return "", c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error uploading file"))
+			return nil, err
 		}
 	}
 
-	res, err := stream.CloseAndRecv()
+	return stream.CloseAndRecv()
+}
+
+// saveImage streams the file to the upload service and inserts the uploaded image into the database.
+func (s *CommandService) saveImage(ctx context.Context, req *models.UploadRequest) (*models.Image, error) {
+	id := uuid.NewString()
+	objectName := utils.ImageObjectName(id, req.Filename)
+	res, err := s.streamData(ctx, req, objectName)
 	if err != nil {
-		return "", c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error uploading file"))
+		log.Printf("Error uploading file: %s", err)
+		return nil, err
+	}
+
+	img := &models.Image{
+		ID:           id,
+		UserID:       req.UserID,
+		Name:         req.Filename,
+		ObjectName:   objectName,
+		FolderID:     req.FolderID,
+		URL:          res.Location,
+		ThumbnailURL: res.Variants[models.ThumbnailVariant],
+		PreviewURL:   res.Variants[models.PreviewVariant],
+		Metadata:     imageMetadata(res.Metadata),
+	}
+
+	if err := database.InsertImage(img); err != nil {
+		log.Printf("Error inserting image: %s", err)
+		return nil, err
 	}
 
-	return res.Location, nil
+	return img, nil
 }
 
 func (s *CommandService) UploadHandler(c *fiber.Ctx) error {
 	req, err := s.getUploadData(c)
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting upload data"))
+	if errors.Is(err, utils.ErrInvalidFolderPath) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid path"))
 	}
 
-	defer req.File.Close()
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid file"))
+	if errors.Is(err, utils.ErrInvalidFilename) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid file name"))
 	}
 
-	location, err := s.streamData(c, req)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error uploading file"))
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting upload data"))
 	}
 
-	img := &models.Image{
-		ID:       uuid.NewString(),
-		UserID:   req.UserID,
-		Name:     req.Filename,
-		FolderID: req.FolderID,
-		URL:      location,
-	}
+	defer req.File.Close()
+	img, err := s.saveImage(c.Context(), req)
+	if err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			return c.Status(http.StatusRequestEntityTooLarge).JSON(utils.JsonError("File too large"))
+		}
 
-	if err := database.InsertImage(img); err != nil {
-		log.Printf("Error inserting image: %s", err)
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error inserting file"))
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error uploading file"))
 	}
 
 	return c.Status(http.StatusOK).JSON(img)
 }
 
+// DeleteImageHandler moves an image to the trash, its files are deleted from the bucket when the trash is purged.
 func (s *CommandService) DeleteImageHandler(c *fiber.Ctx) error {
-	fileName := c.Params("filename")
-	folder := c.Query("path")
-	if folder == "" {
-		folder = "default"
-	}
-
 	id := c.Params("id")
 	if id == "" {
 		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid id"))
 	}
 
-	if err := database.DeleteImage(id); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error deleting image"))
+	image, err := authz.AuthorizeImage(middlewares.UserID(c), id, authz.Delete)
+	if err != nil {
+		return notFoundError(c, err, "Image not found", "Error deleting image")
 	}
 
-	key := fmt.Sprintf("%s/%s/%s", c.Locals("username").(string), folder, fileName)
-	if err := bucket.Delete(key); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error deleting file"))
+	if err := database.TrashImage(image.ID, image.UserID); err != nil {
+		return notFoundError(c, err, "Image not found", "Error deleting image")
 	}
 
-	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Image deleted"})
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Image moved to trash"})
 }
 
+// DeleteFolderHandler deletes a folder with its subfolders and images and removes their objects from the bucket.
 func (s *CommandService) DeleteFolderHandler(c *fiber.Ctx) error {
-	folder := c.Params("folder")
-	if folder == "" {
-		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid folder"))
+	folder, err := authz.AuthorizeFolder(middlewares.UserID(c), c.Params("folderID"), authz.Delete)
+	if err != nil {
+		return notFoundError(c, err, "Folder not found", "Error deleting folder")
 	}
 
-	userId := c.Locals("user_id").(string)
-	if err := database.DeleteFolder(userId, folder); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error deleting folder"))
+	deleted, err := database.DeleteFolderTree(folder.ID, folder.UserID)
+	if err != nil {
+		if errors.Is(err, database.ErrRelocationInProgress) {
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("A folder is being moved"))
+		}
+
+		return notFoundError(c, err, "Folder not found", "Error deleting folder")
+	}
+
+	objects, err := deleteFolderObjects(middlewares.Username(c), deleted)
+	if err != nil {
+		log.Printf("Error deleting the objects of folder %s: %v", folder.ID, err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error deleting folder files"))
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "Folder deleted",
+		"folders": deleted.Folders,
+		"images":  len(deleted.Images),
+		"objects": objects,
+	})
+}
+
+// deleteFolderObjects deletes the objects of a deleted folder and returns how many were deleted, every object under
+// the path of the folder is deleted unless other folders share the prefix, then only the objects of its images are.
+func deleteFolderObjects(username string, deleted *models.DeletedFolder) (int, error) {
+	// the prefix of an account created before the usernames were validated can contain the objects of other users
+	if !deleted.SharedPrefix && utils.ValidUsername(username) {
+		return bucket.DeletePrefix(username + "/" + deleted.Folder.Path + "/")
+	}
+
+	keys := []string{}
+	for _, image := range deleted.Images {
+		keys = append(keys, trashedImageKeys(image)...)
 	}
 
-	if err := bucket.Delete(fmt.Sprintf("%s/%s", c.Locals("username").(string), folder)); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error deleting folder"))
+	if err := forEachKey(keys, bucket.Delete); err != nil {
+		return 0, err
 	}
 
-	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Folder deleted"})
+	return len(keys), nil
 }
 
+// MoveFileHandler moves an image and its variants to another folder, the folder and the object of the image
+// are read from the authorized image so the request can only move the objects of that image.
 func (s *CommandService) MoveFileHandler(c *fiber.Ctx) error {
 	req := new(models.MoveFileRequest)
 	if err := c.BodyParser(req); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid request"))
 	}
 
-	_, err := bucket.MoveFile(c.Locals("username").(string), req.FolderName, req.NewFolderName, req.Filename)
+	var err error
+	if req.NewFolderName, err = utils.CleanFolderPath(req.NewFolderName); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid folder path"))
+	}
+
+	img, err := authz.AuthorizeImage(middlewares.UserID(c), req.FileID, authz.Write)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error moving file"))
+		return notFoundError(c, err, "Image not found", "Error getting image")
+	}
+
+	folder, err := database.GetFolder(img.FolderID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting folder"))
+	}
+
+	// moving an object onto itself would delete it
+	if folder.Path == req.NewFolderName {
+		return c.Status(http.StatusOK).JSON(map[string]string{"message": "File updated"})
 	}
 
-	if err := database.UpdateImage(req, c.Locals("user_id").(string)); err != nil {
-		log.Printf("Error updating image: %s", err)
-		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error updating image"))
+	filenames := []string{img.ObjectName}
+	variants := map[string]string{
+		models.ThumbnailVariant: img.ThumbnailURL,
+		models.PreviewVariant:   img.PreviewURL,
+	}
+
+	for variant, url := range variants {
+		if url != "" {
+			filenames = append(filenames, utils.VariantFilename(img.ObjectName, variant))
+		}
+	}
+
+	// the objects of the folders being relocated are copied by their images, so they must not move meanwhile
+	if err := database.CheckFolderRelocations(img.UserID); err != nil {
+		return moveFileError(c, err)
+	}
+
+	username := middlewares.Username(c)
+	moved := []string{}
+	for _, filename := range filenames {
+		if _, err := bucket.MoveFile(username, folder.Path, req.NewFolderName, filename); err != nil {
+			moveFilesBack(username, folder.Path, req.NewFolderName, moved)
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error moving file"))
+		}
+
+		moved = append(moved, filename)
+	}
+
+	// the image is checked again in the transaction that moves it, the objects are moved back if it fails
+	if err := database.UpdateImage(req, folder.Path, img.UserID); err != nil {
+		moveFilesBack(username, folder.Path, req.NewFolderName, moved)
+		return moveFileError(c, err)
 	}
 
 	return c.Status(http.StatusOK).JSON(map[string]string{"message": "File updated"})
 }
+
+// moveFilesBack moves the objects of an image whose move failed back to its folder.
+func moveFilesBack(username, oldPath, newPath string, filenames []string) {
+	for _, filename := range filenames {
+		if _, err := bucket.MoveFile(username, newPath, oldPath, filename); err != nil {
+			log.Printf("Error moving back file %s: %v", filename, err)
+		}
+	}
+}
+
+// moveFileError returns the response of an image that could not be moved.
+func moveFileError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, database.ErrRelocationInProgress) {
+		return c.Status(http.StatusConflict).JSON(utils.JsonError("A folder is being moved"))
+	}
+
+	log.Printf("Error updating image: %s", err)
+	return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error updating image"))
+}
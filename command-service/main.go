@@ -3,26 +3,72 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/DarioRoman01/photos/middlewares"
 	"github.com/gofiber/fiber/v2"
 )
 
 func main() {
-	app := fiber.New()
+	// behind the reverse proxy the address of the client is read from the header set by the proxy,
+	// the largest bodies are the chunks of the resumable uploads
+	app := fiber.New(fiber.Config{ProxyHeader: os.Getenv("PROXY_HEADER"), BodyLimit: tusMaxChunkSize})
 	commandService, err := NewCommandService()
 	if err != nil {
 		log.Fatalf("Error creating command service: %v", err)
 	}
 
-	app.Use(middlewares.CheckAuthMiddleware())
+	requireVerified, err := middlewares.RequireVerifiedMiddleware()
+	if err != nil {
+		log.Fatalf("Error creating verification middleware: %v", err)
+	}
+
+	// the public routes are registered before the auth middleware, every other route requires a user
 	app.Post("/users/signup", commandService.RegisterHandler)
-	app.Post("/folders/create", commandService.CreateFolderHandler)
 	app.Post("/users/login", commandService.LoginHandler)
+	app.Post("/users/login/2fa", commandService.LoginTwoFactorHandler)
+	app.Get("/users/login/oidc", commandService.OIDCLoginHandler)
+	app.Get("/users/login/oidc/callback", commandService.OIDCCallbackHandler)
+	app.Post("/users/refresh", commandService.RefreshHandler)
+	app.Post("/users/verify", commandService.HandleVerify)
+	app.Post("/users/verify/resend", commandService.ResendVerificationHandler)
+	app.Post("/users/forgot-password", commandService.ForgotPasswordHandler)
+	app.Post("/users/reset-password", commandService.ResetPasswordHandler)
+
+	app.Use(middlewares.CheckAuthMiddleware())
+	app.Use(requireVerified)
+	app.Post("/folders/create", commandService.CreateFolderHandler)
+	app.Post("/users/2fa/enroll", commandService.EnrollTwoFactorHandler)
+	app.Post("/users/2fa/confirm", commandService.ConfirmTwoFactorHandler)
+	app.Post("/users/2fa/disable", commandService.DisableTwoFactorHandler)
+	app.Post("/users/logout", commandService.LogoutHandler)
+	app.Delete("/users/sessions/:id", commandService.RevokeSessionHandler)
+	app.Post("/users/tokens", commandService.CreatePersonalAccessTokenHandler)
+	app.Delete("/users/tokens/:id", commandService.RevokePersonalAccessTokenHandler)
 	app.Post("/images/upload", commandService.UploadHandler)
+	app.Options("/images/uploads", commandService.TusOptionsHandler)
+	app.Post("/images/uploads", commandService.TusCreateHandler)
+	app.Head("/images/uploads/:id", commandService.TusHeadHandler)
+	app.Patch("/images/uploads/:id", commandService.TusPatchHandler)
+	app.Delete("/images/uploads/:id", commandService.TusDeleteHandler)
 	app.Put("/images/move", commandService.MoveFileHandler)
-	app.Post("/users/verify", commandService.HandleVerify)
 	app.Delete("/images/delete/:filename/:id", commandService.DeleteImageHandler)
+	app.Put("/folders/:folderID", commandService.MoveFolderHandler)
+	app.Delete("/folders/:folderID", commandService.DeleteFolderHandler)
+	app.Post("/folders/:folderID/trash", commandService.TrashFolderHandler)
+	app.Post("/trash/images/:id/restore", commandService.RestoreImageHandler)
+	app.Post("/trash/folders/:id/restore", commandService.RestoreFolderHandler)
+	app.Delete("/trash", commandService.EmptyTrashHandler)
+	app.Post("/shares", commandService.CreateShareLinkHandler)
+	app.Delete("/shares/:id", commandService.RevokeShareLinkHandler)
+	app.Post("/albums", commandService.CreateAlbumHandler)
+	app.Put("/albums/:albumID", commandService.UpdateAlbumHandler)
+	app.Delete("/albums/:albumID", commandService.DeleteAlbumHandler)
+	app.Post("/albums/:albumID/images", commandService.AddAlbumImagesHandler)
+	app.Put("/albums/:albumID/images", commandService.ReorderAlbumImagesHandler)
+	app.Delete("/albums/:albumID/images/:imageID", commandService.RemoveAlbumImageHandler)
+	app.Put("/albums/:albumID/collaborators/:username", commandService.SetAlbumCollaboratorHandler)
+	app.Delete("/albums/:albumID/collaborators/:username", commandService.RemoveAlbumCollaboratorHandler)
 
 	app.Listen(":3000")
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/uploadpb"
+)
+
+// imageMetadata converts the metadata returned by the upload service into the image metadata,
+// empty fields are left as nil so they are stored as null.
+func imageMetadata(m *uploadpb.ImageMetadata) models.ImageMetadata {
+	metadata := models.ImageMetadata{}
+	if m == nil {
+		return metadata
+	}
+
+	if takenAt, err := time.Parse(time.RFC3339, m.TakenAt); err == nil {
+		metadata.TakenAt = &takenAt
+	}
+
+	metadata.CameraMake = optionalString(m.CameraMake)
+	metadata.CameraModel = optionalString(m.CameraModel)
+	metadata.Lens = optionalString(m.Lens)
+	metadata.ExposureTime = optionalString(m.ExposureTime)
+	metadata.ISO = optionalInt(m.Iso)
+	metadata.Width = optionalInt(m.Width)
+	metadata.Height = optionalInt(m.Height)
+	metadata.Orientation = optionalInt(m.Orientation)
+
+	if m.FNumber != 0 {
+		metadata.FNumber = &m.FNumber
+	}
+
+	if m.HasLocation {
+		metadata.Latitude = &m.Latitude
+		metadata.Longitude = &m.Longitude
+	}
+
+	return metadata
+}
+
+// optionalString returns nil for empty strings.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+// optionalInt returns nil for zero values.
+func optionalInt(i int32) *int {
+	if i == 0 {
+		return nil
+	}
+
+	n := int(i)
+	return &n
+}
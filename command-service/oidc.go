@@ -0,0 +1,246 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/oidc"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// oidcTokenSize is the number of random bytes of the state, the nonce and the code verifier of a login.
+	oidcTokenSize = 32
+	// oidcCookie is the name of the cookie with the state of a login with the identity provider.
+	oidcCookie = "oidc_state"
+	// oidcCookiePath is the path of the state cookie, it is only sent to the callback.
+	oidcCookiePath = "/users/login/oidc"
+)
+
+// errUnverifiedEmail is returned when the identity provider did not verify the email of a new identity.
+var errUnverifiedEmail = errors.New("the identity provider did not verify the email")
+
+// newOIDCProvider returns the identity provider configured with the OIDC_* env variables, nil if it is not configured.
+func newOIDCProvider() (*oidc.Provider, error) {
+	config, err := oidc.ConfigFromEnv()
+	if err != nil {
+		if errors.Is(err, oidc.ErrNotConfigured) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return oidc.NewProvider(config), nil
+}
+
+// oidcUsernameSuffix is the length of the suffix added to a username of an identity that is already used.
+const oidcUsernameSuffix = 7
+
+// oidcUsername returns an unused username for a user created from an identity, the names of the provider
+// are sanitized because they are the first segment of the keys of the objects of the user.
+func oidcUsername(token *oidc.IDToken) (string, error) {
+	username := utils.SanitizeUsername(token.PreferredUsername, oidcUsernameSuffix)
+	if !utils.ValidUsername(username) {
+		local, _, _ := strings.Cut(token.Email, "@")
+		username = utils.SanitizeUsername(local, oidcUsernameSuffix)
+	}
+
+	if !utils.ValidUsername(username) {
+		username = "user"
+	}
+
+	if _, err := database.GetUserByUsername(username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return username, nil
+		}
+
+		return "", err
+	}
+
+	suffix, err := utils.RandomToken(4)
+	if err != nil {
+		return "", err
+	}
+
+	return username + "-" + strings.ToLower(suffix), nil
+}
+
+// unusablePassword returns the hash of a random password, the user can only login with the identity
+// provider until the password is reset.
+func unusablePassword() (string, error) {
+	password, err := utils.RandomToken(oidcTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.GeneratePassword(utils.GetDefaultPasswordConfig(), password)
+}
+
+// oidcUser returns the user linked to the identity of the id token. A new identity is linked to the user with
+// the same verified email, or to a new user if there is none.
+func oidcUser(issuer string, token *oidc.IDToken) (*models.User, error) {
+	user, err := database.GetUserByIdentity(issuer, token.Subject)
+	if err == nil || !errors.Is(err, sql.ErrNoRows) {
+		return user, err
+	}
+
+	if token.Email == "" || !token.EmailVerified {
+		return nil, errUnverifiedEmail
+	}
+
+	user, err = database.GetUserByEmail(token.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	password, err := unusablePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		username, err := oidcUsername(token)
+		if err != nil {
+			return nil, err
+		}
+
+		user = &models.User{ID: uuid.NewString(), Username: username, Email: token.Email, Password: password}
+		if err := database.InsertUser(user); err != nil {
+			return nil, err
+		}
+	} else if !user.IsVerified {
+		// anyone could have registered the unverified account with the email, so its password
+		// and sessions are discarded before the owner of the email takes it
+		if err := database.UpdateUserPassword(user.ID, user.Password, password); err != nil {
+			return nil, err
+		}
+
+		if err := database.RevokeUserSessions(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !user.IsVerified {
+		if err := database.UpdateUserStatus(user.ID); err != nil {
+			return nil, err
+		}
+
+		user.IsVerified = true
+	}
+
+	identity := &models.Identity{Issuer: issuer, Subject: token.Subject, UserID: user.ID, Email: token.Email}
+	if err := database.InsertIdentity(identity); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// OIDCLoginHandler starts a login with the identity provider, it redirects the user to the provider
+// with a new state, nonce and PKCE challenge that are kept in a cookie until the callback.
+func (s *CommandService) OIDCLoginHandler(c *fiber.Ctx) error {
+	if s.oidc == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.JsonError("OIDC login is not configured"))
+	}
+
+	values := make([]string, 3)
+	for i := range values {
+		value, err := utils.RandomToken(oidcTokenSize)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+		}
+
+		values[i] = value
+	}
+
+	state, nonce, verifier := values[0], values[1], values[2]
+	url, err := s.oidc.AuthCodeURL(c.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("Error discovering identity provider: %v", err)
+		return c.Status(http.StatusBadGateway).JSON(utils.JsonError("Error contacting the identity provider"))
+	}
+
+	stateToken, err := utils.CreateOIDCStateToken(state, nonce, verifier)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	// the callback is a redirect from the provider so the cookie can not be strict
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcCookie,
+		Value:    stateToken,
+		Path:     oidcCookiePath,
+		Expires:  time.Now().Add(utils.OIDCStateTokenTTL),
+		HTTPOnly: true,
+		SameSite: "Lax",
+	})
+
+	return c.Redirect(url, http.StatusFound)
+}
+
+// OIDCCallbackHandler finishes a login with the identity provider, it exchanges the authorization code
+// for the id token of the user and logs in the linked user.
+func (s *CommandService) OIDCCallbackHandler(c *fiber.Ctx) error {
+	if s.oidc == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.JsonError("OIDC login is not configured"))
+	}
+
+	claims, err := utils.VerifyOIDCStateToken(c.Cookies(oidcCookie))
+	c.Cookie(&fiber.Cookie{
+		Name:    oidcCookie,
+		Path:    oidcCookiePath,
+		Expires: time.Now().Add(-time.Hour),
+	})
+
+	if err != nil || c.Query("state") != claims.State {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid state"))
+	}
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		log.Printf("Identity provider login failed: %s %s", providerErr, c.Query("error_description"))
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Login with the identity provider failed"))
+	}
+
+	token, err := s.oidc.Exchange(c.Context(), c.Query("code"), claims.Verifier, claims.Nonce)
+	if err != nil {
+		log.Printf("Error exchanging authorization code: %v", err)
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Login with the identity provider failed"))
+	}
+
+	user, err := oidcUser(s.oidc.Issuer(), token)
+	if err != nil {
+		if errors.Is(err, errUnverifiedEmail) {
+			return c.Status(http.StatusForbidden).JSON(utils.JsonError("The email is not verified by the identity provider"))
+		}
+
+		log.Printf("Error linking identity: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
+	if user.TOTPEnabled {
+		token, err := utils.CreateTwoFactorToken(user.Username, user.ID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+		}
+
+		return c.Status(http.StatusOK).JSON(fiber.Map{"two_factor_required": true, "token": token})
+	}
+
+	accessToken, refreshToken, err := createSession(c, "", user)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	setAuthCookies(c, accessToken, refreshToken)
+	return c.Status(http.StatusOK).JSON(map[string]string{"token": accessToken, "refresh_token": refreshToken})
+}
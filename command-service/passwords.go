@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ForgotPasswordHandler sends a mail with a password reset token to the user with the given email,
+// the response is the same whether the user exists or not so the emails can not be enumerated.
+func (s *CommandService) ForgotPasswordHandler(c *fiber.Ctx) error {
+	input := new(models.ForgotPasswordRequest)
+	if err := c.BodyParser(input); err != nil || input.Email == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	response := map[string]string{"message": "If the email is registered a reset link was sent"}
+	user, err := database.GetUserByEmail(input.Email)
+	if err != nil {
+		return c.Status(http.StatusOK).JSON(response)
+	}
+
+	token, err := utils.CreatePasswordResetToken(user.Username, user.ID, user.Password)
+	if err != nil {
+		log.Printf("Error creating token: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	err = database.EnqueueMail(&models.OutboxMail{
+		ID:       uuid.NewString(),
+		Type:     models.ChangePasswordToken.String(),
+		Receiver: user.Email,
+		Username: user.Username,
+		Token:    token,
+		Locale:   mailLocale(c),
+	})
+
+	if err != nil {
+		log.Printf("Error queueing email: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error sending email"))
+	}
+
+	return c.Status(http.StatusOK).JSON(response)
+}
+
+// ResetPasswordHandler changes the password of the user of a change password token. The token is bound to
+// the password hash it was issued for, so once the password changes the token and every earlier one are rejected.
+func (s *CommandService) ResetPasswordHandler(c *fiber.Ctx) error {
+	input := new(models.ResetPasswordRequest)
+	if err := c.BodyParser(input); err != nil || input.Password == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	claims, err := utils.VerifyToken(input.Token)
+	if err != nil || claims.Type != models.ChangePasswordToken.String() {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid token"))
+	}
+
+	user, err := database.GetUserByID(claims.UserID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid token"))
+	}
+
+	fingerprint, err := utils.PasswordFingerprint(user.Password)
+	if err != nil {
+		log.Printf("Error checking token: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error checking token"))
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.ID), []byte(fingerprint)) != 1 {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid token"))
+	}
+
+	hashPwd, err := utils.GeneratePassword(utils.GetDefaultPasswordConfig(), input.Password)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error generating password"))
+	}
+
+	if err := database.UpdateUserPassword(user.ID, user.Password, hashPwd); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid token"))
+		}
+
+		log.Printf("Error updating password: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error updating password"))
+	}
+
+	// the sessions opened with the old password are logged out
+	if err := database.RevokeUserSessions(user.ID); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Password updated"})
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/bucket"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// relocationLease is the time a relocation can run without renewing its lease before other replica recovers it.
+	relocationLease = 2 * time.Minute
+	// bucketConcurrency is the number of objects copied or deleted in parallel by the folder operations.
+	bucketConcurrency = 8
+	// relocationRecoveryInterval is the time between the checks for interrupted relocations.
+	relocationRecoveryInterval = time.Minute
+)
+
+// relocationPrefixes returns the prefix of the objects of the relocated folder before and after the relocation.
+func relocationPrefixes(relocation *models.FolderRelocation) (string, string) {
+	return relocation.Username + "/" + relocation.OldPath + "/", relocation.Username + "/" + relocation.NewPath + "/"
+}
+
+// forEachKey calls fn for every key with at most bucketConcurrency calls running at the same time,
+// no more calls are started after an error and the first error is returned.
+func forEachKey(keys []string, fn func(key string) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	sem := make(chan struct{}, bucketConcurrency)
+	for _, key := range keys {
+		sem <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := fn(key); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				mu.Unlock()
+			}
+		}(key)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// holdLease renews the lease of the relocation until the returned function is called.
+func holdLease(relocation *models.FolderRelocation) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(relocationLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := database.RenewFolderRelocation(relocation, relocationLease); err != nil {
+					log.Printf("Error renewing the lease of relocation %s: %v", relocation.ID, err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// relocateFolder copies the objects of the images of a journaled relocation to the new prefix, moves the folder in
+// the database and deletes the old objects. The copies are deleted if the folder can not be moved. A relocation
+// interrupted before the folder is moved is rolled back by recoverRelocations and one interrupted after it is completed.
+func relocateFolder(relocation *models.FolderRelocation) (*models.Folder, error) {
+	defer holdLease(relocation)()
+	oldPrefix, newPrefix := relocationPrefixes(relocation)
+
+	// only the objects of the images are copied, a folder in the trash can share the old prefix
+	keys := []string{}
+	images, err := database.GetFolderTreeImages(relocation.FolderID)
+	if err == nil {
+		for _, image := range images {
+			keys = append(keys, trashedImageKeys(image)...)
+		}
+
+		err = forEachKey(keys, func(key string) error {
+			return bucket.Copy(key, newPrefix+strings.TrimPrefix(key, oldPrefix))
+		})
+	}
+
+	var folder *models.Folder
+	if err == nil {
+		folder, err = database.CommitFolderRelocation(relocation, bucket.URL)
+	}
+
+	if err != nil {
+		if rollbackErr := rollbackRelocation(relocation); rollbackErr != nil {
+			log.Printf("Error rolling back relocation %s, it will be recovered: %v", relocation.ID, rollbackErr)
+		}
+
+		return nil, err
+	}
+
+	copied := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		copied[key] = true
+	}
+
+	if err := finishRelocation(relocation, copied); err != nil {
+		log.Printf("Error finishing relocation %s, it will be recovered: %v", relocation.ID, err)
+	}
+
+	return folder, nil
+}
+
+// rollbackRelocation deletes the copies of the objects of a relocation whose folder was not moved and removes
+// the relocation from the journal. Nothing is deleted if the folder was moved meanwhile.
+func rollbackRelocation(relocation *models.FolderRelocation) error {
+	if err := database.AbortFolderRelocation(relocation); err != nil {
+		return err
+	}
+
+	oldPrefix, newPrefix := relocationPrefixes(relocation)
+	keys, err := bucket.List(oldPrefix)
+	if err != nil {
+		return err
+	}
+
+	err = forEachKey(keys, func(key string) error {
+		return bucket.Delete(newPrefix + strings.TrimPrefix(key, oldPrefix))
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return database.DeleteFolderRelocation(relocation)
+}
+
+// finishRelocation deletes the old objects of the images committed by a relocation and removes the relocation from
+// the journal. The objects that were not copied, like the ones uploaded while the folder was relocated, are moved.
+// The other objects under the old prefix, like the ones uploaded to a new folder with the old path after the
+// commit, are not of the relocation and are kept.
+func finishRelocation(relocation *models.FolderRelocation, copied map[string]bool) error {
+	journaled, err := database.GetFolderRelocationKeys(relocation.ID)
+	if err != nil {
+		return err
+	}
+
+	committed := make(map[string]bool, len(journaled))
+	for _, key := range journaled {
+		committed[key] = true
+	}
+
+	// the keys already moved by an interrupted attempt are not listed anymore
+	oldPrefix, newPrefix := relocationPrefixes(relocation)
+	listed, err := bucket.List(oldPrefix)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for _, key := range listed {
+		if committed[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	err = forEachKey(keys, func(key string) error {
+		if !copied[key] {
+			if err := bucket.Copy(key, newPrefix+strings.TrimPrefix(key, oldPrefix)); err != nil {
+				return err
+			}
+		}
+
+		return bucket.Delete(key)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return database.DeleteFolderRelocation(relocation)
+}
+
+// recoverRelocation rolls back or completes a relocation taken over from other worker.
+func recoverRelocation(relocation *models.FolderRelocation) error {
+	defer holdLease(relocation)()
+	if relocation.Status == models.RelocationCommitted {
+		return finishRelocation(relocation, nil)
+	}
+
+	return rollbackRelocation(relocation)
+}
+
+// recoverRelocations periodically takes over the relocations whose lease expired, like the ones of a stopped replica.
+func recoverRelocations() {
+	for range time.Tick(relocationRecoveryInterval) {
+		for {
+			relocation, err := database.ClaimFolderRelocation(relocationLease)
+			if err != nil {
+				if !errors.Is(err, database.ErrNotFound) {
+					log.Printf("Error claiming relocation: %v", err)
+				}
+
+				break
+			}
+
+			if err := recoverRelocation(relocation); err != nil {
+				log.Printf("Error recovering relocation %s: %v", relocation.ID, err)
+			}
+		}
+	}
+}
+
+// MoveFolderHandler renames a folder and moves it into other folder or into the root, the objects of the folder
+// and its descendants are moved to the new path in the bucket.
+func (s *CommandService) MoveFolderHandler(c *fiber.Ctx) error {
+	req := new(models.MoveFolderRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid request"))
+	}
+
+	if req.Name != "" && !utils.ValidFolderName(req.Name) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid folder name"))
+	}
+
+	// the objects are moved by their prefix, which is not only of the user if its username is not a valid segment
+	if !utils.ValidUsername(middlewares.Username(c)) {
+		return c.Status(http.StatusConflict).JSON(utils.JsonError("The folders of this account can not be moved"))
+	}
+
+	folder, err := authz.AuthorizeFolder(middlewares.UserID(c), c.Params("folderID"), authz.Write)
+	if err != nil {
+		return notFoundError(c, err, "Folder not found", "Error moving folder")
+	}
+
+	relocation := &models.FolderRelocation{
+		ID:       uuid.NewString(),
+		UserID:   folder.UserID,
+		Username: middlewares.Username(c),
+		FolderID: folder.ID,
+		ParentID: folder.ParentID,
+		Name:     folder.Name,
+		LeaseID:  uuid.NewString(),
+	}
+
+	if req.Name != "" {
+		relocation.Name = req.Name
+	}
+
+	if req.ParentID != nil {
+		relocation.ParentID = nil
+		if *req.ParentID != "" {
+			parent, err := authz.AuthorizeFolder(middlewares.UserID(c), *req.ParentID, authz.Write)
+			if err != nil {
+				return notFoundError(c, err, "Folder not found", "Error moving folder")
+			}
+
+			relocation.ParentID = &parent.ID
+		}
+	}
+
+	if err := database.CreateFolderRelocation(relocation, relocationLease); err != nil {
+		switch {
+		case errors.Is(err, database.ErrFolderExists):
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("Folder already exists"))
+		case errors.Is(err, database.ErrRelocationInProgress):
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("Another folder is being moved"))
+		case errors.Is(err, database.ErrFolderCycle):
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("A folder can not be moved into itself"))
+		}
+
+		return notFoundError(c, err, "Folder not found", "Error moving folder")
+	}
+
+	if relocation.OldPath == relocation.NewPath {
+		return c.Status(http.StatusOK).JSON(folder)
+	}
+
+	folder, err = relocateFolder(relocation)
+	if errors.Is(err, database.ErrFolderExists) {
+		return c.Status(http.StatusConflict).JSON(utils.JsonError("Folder already exists"))
+	}
+
+	if err != nil {
+		log.Printf("Error relocating folder %s: %v", relocation.FolderID, err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error moving folder"))
+	}
+
+	return c.Status(http.StatusOK).JSON(folder)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	// refreshTokenSize is the number of random bytes of a refresh token.
+	refreshTokenSize = 32
+	// refreshCookie is the name of the cookie with the refresh token, it is only sent to the users routes.
+	refreshCookie = "refresh_token"
+	// refreshCookiePath is the path of the refresh token cookie.
+	refreshCookiePath = "/users"
+)
+
+// createSession starts a new session of the user on the device of the request and returns its access and refresh tokens.
+func createSession(c *fiber.Ctx, device string, user *models.User) (string, string, error) {
+	if device == "" {
+		device = c.Get(fiber.HeaderUserAgent)
+	}
+
+	if len(device) > 255 {
+		device = device[:255]
+	}
+
+	refreshToken, err := utils.RandomToken(refreshTokenSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	session := &models.Session{
+		ID:     uuid.NewString(),
+		UserID: user.ID,
+		Device: device,
+		IP:     c.IP(),
+	}
+
+	if err := database.InsertSession(session, utils.HashToken(refreshToken), utils.RefreshTokenTTL); err != nil {
+		return "", "", err
+	}
+
+	token, err := utils.CreateAccessToken(user.Username, user.ID, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+// setAuthCookies sets the access and refresh token cookies.
+func setAuthCookies(c *fiber.Ctx, token, refreshToken string) {
+	c.Cookie(&fiber.Cookie{
+		Name:    "token",
+		Value:   token,
+		Expires: time.Now().Add(utils.AccessTokenTTL),
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookie,
+		Value:    refreshToken,
+		Path:     refreshCookiePath,
+		Expires:  time.Now().Add(utils.RefreshTokenTTL),
+		HTTPOnly: true,
+		SameSite: "Strict",
+	})
+}
+
+// clearAuthCookies removes the access and refresh token cookies.
+func clearAuthCookies(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:    "token",
+		Value:   "",
+		Expires: time.Now().Add(-time.Hour),
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshCookie,
+		Value:    "",
+		Path:     refreshCookiePath,
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		SameSite: "Strict",
+	})
+}
+
+// RefreshHandler exchanges a refresh token for a new access token and a new refresh token, every refresh
+// token can be used once and using it again revokes its session.
+func (s *CommandService) RefreshHandler(c *fiber.Ctx) error {
+	input := new(models.RefreshRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(input); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+		}
+	}
+
+	if input.RefreshToken == "" {
+		input.RefreshToken = c.Cookies(refreshCookie)
+	}
+
+	if input.RefreshToken == "" {
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Unauthorized"))
+	}
+
+	refreshToken, err := utils.RandomToken(refreshTokenSize)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	session, err := database.RotateRefreshToken(utils.HashToken(input.RefreshToken), utils.HashToken(refreshToken), utils.RefreshTokenTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrTokenReused):
+			log.Printf("Refresh token reused, session revoked")
+			clearAuthCookies(c)
+			return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Session revoked"))
+		case errors.Is(err, database.ErrNotFound):
+			return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Unauthorized"))
+		default:
+			log.Printf("Error refreshing token: %v", err)
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error refreshing token"))
+		}
+	}
+
+	token, err := utils.CreateAccessToken(session.Username, session.UserID, session.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	setAuthCookies(c, token, refreshToken)
+	return c.Status(http.StatusOK).JSON(map[string]string{"token": token, "refresh_token": refreshToken})
+}
+
+// RevokeSessionHandler revokes a session of the user, the device of the session is logged out.
+func (s *CommandService) RevokeSessionHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if err := database.RevokeSession(c.Params("id"), userID); err != nil {
+		return notFoundError(c, err, "Session not found", "Error revoking session")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Session revoked"})
+}
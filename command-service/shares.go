@@ -0,0 +1,91 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// shareTokenSize is the number of random bytes of a share link token.
+const shareTokenSize = 32
+
+// CreateShareLinkHandler creates a public link to an image or a folder of the user.
+func (s *CommandService) CreateShareLinkHandler(c *fiber.Ctx) error {
+	req := new(models.CreateShareLinkRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	if (req.ImageID == "") == (req.FolderID == "") {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Either image_id or folder_id is required"))
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid expires_at"))
+	}
+
+	userID := middlewares.UserID(c)
+	link := &models.ShareLink{ID: uuid.NewString(), UserID: userID}
+	if req.ImageID != "" {
+		image, err := authz.AuthorizeImage(middlewares.UserID(c), req.ImageID, authz.Share)
+		if err != nil {
+			return notFoundError(c, err, "Image not found", "Error getting image")
+		}
+
+		link.ImageID = &image.ID
+	} else {
+		folder, err := authz.AuthorizeFolder(middlewares.UserID(c), req.FolderID, authz.Share)
+		if err != nil {
+			return notFoundError(c, err, "Folder not found", "Error getting folder")
+		}
+
+		link.FolderID = &folder.ID
+	}
+
+	if req.ExpiresAt != nil {
+		// the column has no time zone so the expiration is always stored in utc
+		expiresAt := req.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
+
+	if req.Password != "" {
+		hashPwd, err := utils.GeneratePassword(utils.GetDefaultPasswordConfig(), req.Password)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error generating password"))
+		}
+
+		link.Password = hashPwd
+		link.HasPassword = true
+	}
+
+	token, err := utils.RandomToken(shareTokenSize)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	link.Token = token
+	if err := database.InsertShareLink(link); err != nil {
+		log.Printf("Error inserting share link: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating share link"))
+	}
+
+	return c.Status(http.StatusCreated).JSON(link)
+}
+
+// RevokeShareLinkHandler revokes a share link of the user, the link stops resolving immediately.
+func (s *CommandService) RevokeShareLinkHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if err := database.RevokeShareLink(c.Params("id"), userID); err != nil {
+		return notFoundError(c, err, "Share link not found", "Error revoking share link")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Share link revoked"})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DarioRoman01/photos/ratelimit"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// throttle throttles the attempts of an action, like the failed logins, per ip and per account.
+type throttle struct {
+	ips      *ratelimit.Limiter // ips limits the attempts of every ip.
+	accounts *ratelimit.Limiter // accounts limits the attempts of every account.
+}
+
+// newThrottle creates the limiters of the action with the given name in the store.
+func newThrottle(store ratelimit.Store, name string, ipPolicy, accountPolicy ratelimit.Policy) *throttle {
+	return &throttle{
+		ips:      ratelimit.NewLimiter(store, name+":ip:", ipPolicy),
+		accounts: ratelimit.NewLimiter(store, name+":account:", accountPolicy),
+	}
+}
+
+// accountKey returns the key of an account, the emails are compared without case.
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// attempt counts an attempt of the ip and the account before it is verified, so parallel requests can not skip
+// the waits, and returns the longest wait of the ip and the account. The attempt is not counted when it must wait.
+func (t *throttle) attempt(ip, account string) (time.Duration, error) {
+	wait, err := t.ips.Attempt(ip)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+
+	wait, err = t.accounts.Attempt(account)
+	if err != nil || wait > 0 {
+		if releaseErr := t.ips.Release(ip); err == nil {
+			err = releaseErr
+		}
+	}
+
+	return wait, err
+}
+
+// release uncounts an attempt of the ip and the account that did not fail.
+func (t *throttle) release(ip, account string) error {
+	if err := t.ips.Release(ip); err != nil {
+		return err
+	}
+
+	return t.accounts.Release(account)
+}
+
+// succeed uncounts the attempt of the ip and forgets the failures of the account, the other failures of the ip
+// are kept so an attacker can not reset them logging in with its own account.
+func (t *throttle) succeed(ip, account string) error {
+	if err := t.ips.Release(ip); err != nil {
+		return err
+	}
+
+	return t.accounts.Reset(account)
+}
+
+// tooManyAttempts returns the response of a throttled request.
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return c.Status(http.StatusTooManyRequests).JSON(utils.JsonError("Too many attempts, try again later"))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns a password hash compared when the email of a login does not exist,
+// so the response time does not tell which emails are registered.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.GeneratePassword(utils.GetDefaultPasswordConfig(), "dummy password")
+	})
+
+	return dummyHash
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// personalTokenSize is the number of random bytes of a personal access token.
+const personalTokenSize = 32
+
+// validScope checks if the given scope is a known token scope.
+func validScope(scope string) bool {
+	for _, s := range models.TokenScopes {
+		if string(s) == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreatePersonalAccessTokenHandler creates a personal access token, the plaintext token is only returned in this response.
+func (s *CommandService) CreatePersonalAccessTokenHandler(c *fiber.Ctx) error {
+	req := new(models.CreatePersonalAccessTokenRequest)
+	if err := c.BodyParser(req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	if req.Name == "" || len(req.Name) > 255 {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid name"))
+	}
+
+	if len(req.Scopes) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("At least one scope is required"))
+	}
+
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid scope " + scope))
+		}
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid expires_at"))
+	}
+
+	raw, err := utils.RandomToken(personalTokenSize)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	token := &models.PersonalAccessToken{
+		ID:     uuid.NewString(),
+		UserID: middlewares.UserID(c),
+		Name:   req.Name,
+		Token:  models.PersonalTokenPrefix + raw,
+		Scopes: req.Scopes,
+	}
+
+	if req.ExpiresAt != nil {
+		// the column has no time zone so the expiration is always stored in utc
+		expiresAt := req.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := database.InsertPersonalAccessToken(token, utils.HashToken(token.Token)); err != nil {
+		log.Printf("Error inserting personal access token: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	return c.Status(http.StatusCreated).JSON(token)
+}
+
+// RevokePersonalAccessTokenHandler revokes a personal access token of the user.
+func (s *CommandService) RevokePersonalAccessTokenHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if err := database.RevokePersonalAccessToken(c.Params("id"), userID); err != nil {
+		return notFoundError(c, err, "Token not found", "Error revoking token")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Token revoked"})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/bucket"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// purgeBatchSize is the number of images deleted from the bucket in every purge query.
+const purgeBatchSize = 100
+
+// trashConfig is the configuration of the trash.
+type trashConfig struct {
+	retention     time.Duration // retention is the time the items are kept in the trash before they are deleted.
+	purgeInterval time.Duration // purgeInterval is the time between the runs of the purger.
+}
+
+// newTrashConfig reads the trash configuration from the env variables.
+func newTrashConfig() (*trashConfig, error) {
+	config := &trashConfig{retention: 30 * 24 * time.Hour, purgeInterval: time.Hour}
+	if retention := os.Getenv("TRASH_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRASH_RETENTION: %w", err)
+		}
+
+		config.retention = d
+	}
+
+	if interval := os.Getenv("TRASH_PURGE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid TRASH_PURGE_INTERVAL: %s", interval)
+		}
+
+		config.purgeInterval = d
+	}
+
+	return config, nil
+}
+
+// trashedImageKeys returns the bucket keys of the image file and its variants.
+func trashedImageKeys(image *models.TrashedImage) []string {
+	keys := []string{fmt.Sprintf("%s/%s/%s", image.Username, image.FolderName, image.ObjectName)}
+	variants := map[string]string{
+		models.ThumbnailVariant: image.ThumbnailURL,
+		models.PreviewVariant:   image.PreviewURL,
+	}
+
+	for variant, url := range variants {
+		if url != "" {
+			keys = append(keys, fmt.Sprintf("%s/%s/%s", image.Username, image.FolderName, utils.VariantFilename(image.ObjectName, variant)))
+		}
+	}
+
+	return keys
+}
+
+// purge deletes from the bucket and the database the items that are in the trash for at least the given time,
+// if userID is empty the items of every user are deleted.
+func purge(userID string, age time.Duration) error {
+	for {
+		images, err := database.GetPurgeableImages(userID, age, purgeBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images {
+			for _, key := range trashedImageKeys(image) {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+
+			if err := database.DeleteImage(image.ID, image.UserID); err != nil {
+				return err
+			}
+		}
+
+		if len(images) < purgeBatchSize {
+			break
+		}
+	}
+
+	return database.PurgeFolders(userID, age)
+}
+
+// purgeExpired periodically deletes the items that are in the trash for longer than the retention.
+func (s *CommandService) purgeExpired() {
+	for range time.Tick(s.trash.purgeInterval) {
+		if err := purge("", s.trash.retention); err != nil {
+			log.Printf("Error purging trash: %v", err)
+		}
+	}
+}
+
+// notFoundError returns the response for an error of an operation on a row of the user,
+// ErrNotFound and the denied accesses are returned as 404 and any other error as 500.
+func notFoundError(c *fiber.Ctx, err error, notFound, message string) error {
+	if errors.Is(err, database.ErrNotFound) || errors.Is(err, authz.ErrNotFound) {
+		return c.Status(http.StatusNotFound).JSON(utils.JsonError(notFound))
+	}
+
+	log.Printf("%s: %v", message, err)
+	return c.Status(http.StatusInternalServerError).JSON(utils.JsonError(message))
+}
+
+// TrashFolderHandler moves a folder and its images to the trash.
+func (s *CommandService) TrashFolderHandler(c *fiber.Ctx) error {
+	folder, err := authz.AuthorizeFolder(middlewares.UserID(c), c.Params("folderID"), authz.Delete)
+	if err != nil {
+		return notFoundError(c, err, "Folder not found", "Error deleting folder")
+	}
+
+	if err := database.TrashFolder(folder.ID, folder.UserID); err != nil {
+		if errors.Is(err, database.ErrRelocationInProgress) {
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("A folder is being moved"))
+		}
+
+		return notFoundError(c, err, "Folder not found", "Error deleting folder")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Folder moved to trash"})
+}
+
+// RestoreImageHandler moves an image out of the trash.
+func (s *CommandService) RestoreImageHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if err := database.RestoreImage(c.Params("id"), userID); err != nil {
+		return notFoundError(c, err, "Image not found", "Error restoring image")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Image restored"})
+}
+
+// RestoreFolderHandler moves a folder and the images trashed with it out of the trash.
+func (s *CommandService) RestoreFolderHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	if err := database.RestoreFolder(c.Params("id"), userID); err != nil {
+		return notFoundError(c, err, "Folder not found", "Error restoring folder")
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Folder restored"})
+}
+
+// EmptyTrashHandler permanently deletes every item in the trash of the user.
+func (s *CommandService) EmptyTrashHandler(c *fiber.Ctx) error {
+	// purge empties the trash of every user for an empty id
+	userID := middlewares.UserID(c)
+	if userID == "" {
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Unauthorized"))
+	}
+
+	if err := purge(userID, 0); err != nil {
+		log.Printf("Error emptying trash: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error emptying trash"))
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Trash emptied"})
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// tusVersion is the version of the tus protocol supported by the resumable upload endpoints.
+	tusVersion = "1.0.0"
+	// tusExtensions are the tus protocol extensions supported by the resumable upload endpoints.
+	tusExtensions = "creation,termination,expiration"
+	// tusContentType is the content type of the chunks sent with PATCH requests.
+	tusContentType = "application/offset+octet-stream"
+	// tusMaxChunkSize is the maximum size of the chunks sent with PATCH requests, it is the body limit of the server
+	// because the chunks are read in memory.
+	tusMaxChunkSize = 8 * 1024 * 1024
+)
+
+// tusConfig is the configuration of the resumable upload endpoints.
+type tusConfig struct {
+	maxSize    int64         // maxSize is the maximum size of an upload in bytes, the one of the upload service.
+	expiration time.Duration // expiration is the time an unfinished upload is kept.
+}
+
+// newTusConfig reads the resumable uploads configuration from the env variables.
+func newTusConfig() (*tusConfig, error) {
+	// the upload service rejects the larger files once they are complete, so they are rejected on creation
+	maxSize, err := utils.UploadMaxSize()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tusConfig{maxSize: maxSize, expiration: 24 * time.Hour}
+
+	if expiration := os.Getenv("TUS_UPLOAD_EXPIRATION"); expiration != "" {
+		d, err := time.ParseDuration(expiration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TUS_UPLOAD_EXPIRATION: %w", err)
+		}
+
+		config.expiration = d
+	}
+
+	return config, nil
+}
+
+// parseUploadMetadata parses the Upload-Metadata header, a comma separated list of keys and base64 encoded values.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// tusHeaders sets the headers included in every resumable upload response.
+func tusHeaders(c *fiber.Ctx) {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set("Cache-Control", "no-store")
+}
+
+// checkTusVersion verifies that the client speaks the supported version of the protocol.
+func checkTusVersion(c *fiber.Ctx) bool {
+	tusHeaders(c)
+	if c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return false
+	}
+
+	return true
+}
+
+// getUpload returns the upload in the request params if it belongs to the current user.
+func (s *CommandService) getUpload(c *fiber.Ctx) (*resumableUpload, error) {
+	id := c.Params("id")
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.uploads.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.UserID != middlewares.UserID(c) {
+		return nil, os.ErrNotExist
+	}
+
+	return upload, nil
+}
+
+// uploadExpires returns the value of the Upload-Expires header for the given upload.
+func (s *CommandService) uploadExpires(upload *resumableUpload) string {
+	return upload.CreatedAt.Add(s.tus.expiration).UTC().Format(http.TimeFormat)
+}
+
+// TusOptionsHandler describes the resumable upload capabilities of the server.
+func (s *CommandService) TusOptionsHandler(c *fiber.Ctx) error {
+	tusHeaders(c)
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	if s.tus.maxSize > 0 {
+		c.Set("Tus-Max-Size", strconv.FormatInt(s.tus.maxSize, 10))
+	}
+
+	// not part of the protocol, the clients use it to pick their chunk size
+	c.Set("Tus-Max-Chunk-Size", strconv.Itoa(tusMaxChunkSize))
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// TusCreateHandler creates a new resumable upload, the file name and the folder are read from the upload metadata.
+func (s *CommandService) TusCreateHandler(c *fiber.Ctx) error {
+	if !checkTusVersion(c) {
+		return c.Status(http.StatusPreconditionFailed).JSON(utils.JsonError("Unsupported tus version"))
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid Upload-Length"))
+	}
+
+	if s.tus.maxSize > 0 && length > s.tus.maxSize {
+		return c.Status(http.StatusRequestEntityTooLarge).JSON(utils.JsonError("Upload too large"))
+	}
+
+	metadata, err := parseUploadMetadata(c.Get("Upload-Metadata"))
+	if err != nil || metadata["filename"] == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid Upload-Metadata"))
+	}
+
+	if !utils.ValidFilename(metadata["filename"]) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid file name"))
+	}
+
+	folder := metadata["folder"]
+	if folder == "" {
+		folder = c.Query("path")
+	}
+
+	folder, err = utils.CleanFolderPath(folder)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid folder"))
+	}
+
+	upload := &resumableUpload{
+		ID:        uuid.NewString(),
+		UserID:    middlewares.UserID(c),
+		Username:  middlewares.Username(c),
+		Folder:    folder,
+		Filename:  metadata["filename"],
+		Length:    length,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.uploads.create(upload); err != nil {
+		log.Printf("Error creating upload: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating upload"))
+	}
+
+	c.Set("Location", fmt.Sprintf("%s/images/uploads/%s", c.BaseURL(), upload.ID))
+	c.Set("Upload-Expires", s.uploadExpires(upload))
+	return c.SendStatus(http.StatusCreated)
+}
+
+// TusHeadHandler returns the current offset of a resumable upload.
+func (s *CommandService) TusHeadHandler(c *fiber.Ctx) error {
+	if !checkTusVersion(c) {
+		return c.SendStatus(http.StatusPreconditionFailed)
+	}
+
+	upload, err := s.getUpload(c)
+	if err != nil {
+		return c.SendStatus(http.StatusNotFound)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Set("Upload-Expires", s.uploadExpires(upload))
+	return c.SendStatus(http.StatusOK)
+}
+
+// TusPatchHandler appends a chunk to a resumable upload, once all the bytes are received
+// the file is sent to the upload service and the image is saved.
+func (s *CommandService) TusPatchHandler(c *fiber.Ctx) error {
+	if !checkTusVersion(c) {
+		return c.Status(http.StatusPreconditionFailed).JSON(utils.JsonError("Unsupported tus version"))
+	}
+
+	if c.Get(fiber.HeaderContentType) != tusContentType {
+		return c.Status(http.StatusUnsupportedMediaType).JSON(utils.JsonError("Invalid Content-Type"))
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid Upload-Offset"))
+	}
+
+	defer s.uploads.lock(c.Params("id"))()
+	upload, err := s.getUpload(c)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(utils.JsonError("Upload not found"))
+	}
+
+	newOffset, err := s.uploads.write(upload, offset, bytes.NewReader(c.Body()))
+	c.Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Set("Upload-Expires", s.uploadExpires(upload))
+	if err != nil {
+		switch {
+		case errors.Is(err, errOffsetMismatch):
+			return c.Status(http.StatusConflict).JSON(utils.JsonError("Upload-Offset does not match the upload offset"))
+		case errors.Is(err, errUploadTooLarge):
+			return c.Status(http.StatusRequestEntityTooLarge).JSON(utils.JsonError("Chunk exceeds the upload length"))
+		default:
+			log.Printf("Error writing upload chunk: %v", err)
+			return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error writing chunk"))
+		}
+	}
+
+	if newOffset < upload.Length {
+		return c.SendStatus(http.StatusNoContent)
+	}
+
+	// the upload is complete, if saving the image fails the data is kept so the
+	// client can finish the upload sending an empty chunk at the final offset
+	img, err := s.finishUpload(c, upload)
+	if err != nil {
+		if status.Code(err) == codes.ResourceExhausted {
+			return c.Status(http.StatusRequestEntityTooLarge).JSON(utils.JsonError("File too large"))
+		}
+
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error uploading file"))
+	}
+
+	if err := s.uploads.remove(upload.ID); err != nil {
+		log.Printf("Error removing finished upload: %v", err)
+	}
+
+	c.Set("Image-Id", img.ID)
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// finishUpload sends a completed upload to the upload service and saves the image.
+func (s *CommandService) finishUpload(c *fiber.Ctx, upload *resumableUpload) (*models.Image, error) {
+	folderId, err := database.CheckFolder(upload.UserID, upload.Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.uploads.open(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	return s.saveImage(c.Context(), &models.UploadRequest{
+		FolderName: upload.Folder,
+		FolderID:   folderId,
+		UserID:     upload.UserID,
+		Username:   upload.Username,
+		Filename:   upload.Filename,
+		File:       file,
+	})
+}
+
+// TusDeleteHandler terminates a resumable upload and removes the data received so far.
+func (s *CommandService) TusDeleteHandler(c *fiber.Ctx) error {
+	if !checkTusVersion(c) {
+		return c.Status(http.StatusPreconditionFailed).JSON(utils.JsonError("Unsupported tus version"))
+	}
+
+	defer s.uploads.lock(c.Params("id"))()
+	upload, err := s.getUpload(c)
+	if err != nil {
+		return c.Status(http.StatusNotFound).JSON(utils.JsonError("Upload not found"))
+	}
+
+	if err := s.uploads.remove(upload.ID); err != nil {
+		log.Printf("Error removing upload: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error removing upload"))
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
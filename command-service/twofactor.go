@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// recoveryCodesCount is the number of recovery codes generated when the TOTP is enabled.
+const recoveryCodesCount = 10
+
+// errInvalidCode is returned when a TOTP or recovery code is not valid.
+var errInvalidCode = errors.New("invalid code")
+
+// totpIssuer returns the issuer shown by the authenticator apps.
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+
+	return "Photos"
+}
+
+// checkTOTP validates a TOTP code of the user and records its time step so it can not be used again.
+func checkTOTP(user *models.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return errInvalidCode
+	}
+
+	if err := database.UseTOTPStep(user.ID, step); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return errInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// checkTwoFactorCode validates a TOTP code or, if the code is not a TOTP code, a recovery code of the user.
+func checkTwoFactorCode(user *models.User, code string) error {
+	if len(code) == 6 {
+		return checkTOTP(user, code)
+	}
+
+	err := database.UseRecoveryCode(user.ID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if errors.Is(err, database.ErrNotFound) {
+		return errInvalidCode
+	}
+
+	return err
+}
+
+// codeError returns the response for an error of a code check.
+func codeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errInvalidCode) {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid code"))
+	}
+
+	log.Printf("Error checking code: %v", err)
+	return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error checking code"))
+}
+
+// EnrollTwoFactorHandler generates a new TOTP secret for the user, it is required at login once it is confirmed.
+func (s *CommandService) EnrollTwoFactorHandler(c *fiber.Ctx) error {
+	user, err := database.GetUserByID(middlewares.UserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(http.StatusConflict).JSON(utils.JsonError("Two factor authentication is already enabled"))
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error generating secret"))
+	}
+
+	if err := database.SetTOTPSecret(user.ID, secret); err != nil {
+		log.Printf("Error storing totp secret: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error generating secret"))
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{
+		"secret": secret,
+		"uri":    utils.TOTPURI(totpIssuer(), user.Email, secret),
+	})
+}
+
+// ConfirmTwoFactorHandler enables the TOTP of the user with a code of the enrolled secret
+// and returns the recovery codes, they are only shown in this response.
+func (s *CommandService) ConfirmTwoFactorHandler(c *fiber.Ctx) error {
+	input := new(models.TwoFactorRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	user, err := database.GetUserByID(middlewares.UserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
+	if user.TOTPEnabled {
+		return c.Status(http.StatusConflict).JSON(utils.JsonError("Two factor authentication is already enabled"))
+	}
+
+	if user.TOTPSecret == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Two factor authentication is not enrolled"))
+	}
+
+	if err := checkTOTP(user, input.Code); err != nil {
+		return codeError(c, err)
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error generating recovery codes"))
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(code)
+	}
+
+	if err := database.EnableTOTP(user.ID, hashes); err != nil {
+		log.Printf("Error enabling totp: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error enabling two factor authentication"))
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// DisableTwoFactorHandler disables the TOTP of the user with a TOTP or recovery code.
+func (s *CommandService) DisableTwoFactorHandler(c *fiber.Ctx) error {
+	input := new(models.TwoFactorRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	user, err := database.GetUserByID(middlewares.UserID(c))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error getting user"))
+	}
+
+	if !user.TOTPEnabled {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Two factor authentication is not enabled"))
+	}
+
+	if err := checkTwoFactorCode(user, input.Code); err != nil {
+		return codeError(c, err)
+	}
+
+	if err := database.DisableTOTP(user.ID); err != nil {
+		log.Printf("Error disabling totp: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error disabling two factor authentication"))
+	}
+
+	return c.Status(http.StatusOK).JSON(map[string]string{"message": "Two factor authentication disabled"})
+}
+
+// LoginTwoFactorHandler is the second step of the login of a user with TOTP, it exchanges the intermediate
+// token of the first step and a TOTP or recovery code for an access token.
+func (s *CommandService) LoginTwoFactorHandler(c *fiber.Ctx) error {
+	input := new(models.TwoFactorRequest)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	claims, err := utils.VerifyToken(input.Token)
+	if err != nil || claims.Type != models.TwoFactorToken.String() {
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Invalid token"))
+	}
+
+	user, err := database.GetUserByID(claims.UserID)
+	if err != nil || !user.TOTPEnabled {
+		return c.Status(http.StatusUnauthorized).JSON(utils.JsonError("Invalid token"))
+	}
+
+	// the codes are throttled with the password attempts of the account
+	account := accountKey(user.Email)
+	wait, err := s.logins.attempt(c.IP(), account)
+	if err != nil {
+		log.Printf("Error counting login attempt: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error checking code"))
+	}
+
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	if err := checkTwoFactorCode(user, input.Code); err != nil {
+		// only the wrong codes count as failed attempts
+		if !errors.Is(err, errInvalidCode) {
+			if err := s.logins.release(c.IP(), account); err != nil {
+				log.Printf("Error releasing login attempt: %v", err)
+			}
+		}
+
+		return codeError(c, err)
+	}
+
+	if err := s.logins.succeed(c.IP(), account); err != nil {
+		log.Printf("Error resetting login attempts: %v", err)
+	}
+
+	token, refreshToken, err := createSession(c, input.Device, user)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	setAuthCookies(c, token, refreshToken)
+	return c.Status(http.StatusOK).JSON(map[string]string{"token": token, "refresh_token": refreshToken})
+}
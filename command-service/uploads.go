@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// errOffsetMismatch is returned when a chunk does not start at the current offset of the upload.
+	errOffsetMismatch = errors.New("offset mismatch")
+	// errUploadTooLarge is returned when a chunk exceeds the declared length of the upload.
+	errUploadTooLarge = errors.New("upload exceeds its length")
+)
+
+// resumableUpload is the state of an in progress resumable upload.
+type resumableUpload struct {
+	ID        string    `json:"id"`         // ID is unique identifier for the upload.
+	UserID    string    `json:"user_id"`    // UserID is the ID of the user who creates the upload.
+	Username  string    `json:"username"`   // Username is the username of the user who creates the upload.
+	Folder    string    `json:"folder"`     // Folder is the name of the folder to upload to.
+	Filename  string    `json:"filename"`   // Filename is the name of the uploaded file.
+	Length    int64     `json:"length"`     // Length is the total size of the file in bytes.
+	Offset    int64     `json:"-"`          // Offset is the number of bytes received so far.
+	CreatedAt time.Time `json:"created_at"` // CreatedAt is the time the upload was created.
+}
+
+// uploadStore keeps the resumable uploads in a directory, every upload has an info file
+// with its state and a data file with the bytes received so far.
+type uploadStore struct {
+	dir   string   // dir is the directory where the uploads are stored.
+	locks sync.Map // locks serializes the writes to the same upload.
+}
+
+// newUploadStore creates a new upload store in the given directory.
+func newUploadStore(dir string) (*uploadStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &uploadStore{dir: dir}, nil
+}
+
+func (s *uploadStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+func (s *uploadStore) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+// lock locks the given upload and returns the function that unlocks it.
+func (s *uploadStore) lock(id string) func() {
+	// the id is copied because the request params are only valid during the request
+	mu, _ := s.locks.LoadOrStore(string([]byte(id)), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// create stores a new empty upload.
+func (s *uploadStore) create(upload *resumableUpload) error {
+	info, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(s.dataPath(upload.ID), nil, 0600); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.infoPath(upload.ID), info, 0600)
+}
+
+// get returns the upload with the given id, its offset is the size of the data received so far.
+func (s *uploadStore) get(id string) (*resumableUpload, error) {
+	info, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	upload := new(resumableUpload)
+	if err := json.Unmarshal(info, upload); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(s.dataPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	upload.Offset = stat.Size()
+	return upload, nil
+}
+
+// write appends the chunk to the upload if it starts at the current offset and returns the new offset.
+// The bytes written before an error are kept, so the client can resume from the new offset.
+// The caller must hold the lock of the upload.
+func (s *uploadStore) write(upload *resumableUpload, offset int64, chunk io.Reader) (int64, error) {
+	if upload.Offset != offset {
+		return upload.Offset, errOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.dataPath(upload.ID), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return offset, err
+	}
+
+	defer f.Close()
+	remaining := upload.Length - offset
+	n, err := io.Copy(f, io.LimitReader(chunk, remaining+1))
+	if n > remaining {
+		// the extra byte read to detect the overflow is discarded
+		if err := f.Truncate(upload.Length); err != nil {
+			return offset, err
+		}
+
+		return upload.Length, errUploadTooLarge
+	}
+
+	return offset + n, err
+}
+
+// open opens the data of the given upload for reading.
+func (s *uploadStore) open(id string) (*os.File, error) {
+	return os.Open(s.dataPath(id))
+}
+
+// remove deletes the given upload, the caller must hold the lock of the upload.
+func (s *uploadStore) remove(id string) error {
+	if err := os.Remove(s.infoPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Remove(s.dataPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	s.locks.Delete(id)
+	return nil
+}
+
+// removeExpired periodically deletes the uploads older than the given expiration.
+func (s *uploadStore) removeExpired(expiration time.Duration) {
+	for range time.Tick(time.Hour) {
+		infos, err := filepath.Glob(filepath.Join(s.dir, "*.info"))
+		if err != nil {
+			log.Printf("Error listing uploads: %v", err)
+			continue
+		}
+
+		for _, info := range infos {
+			id := filepath.Base(info[:len(info)-len(".info")])
+			unlock := s.lock(id)
+			upload, err := s.get(id)
+			if err == nil && time.Since(upload.CreatedAt) > expiration {
+				if err := s.remove(id); err != nil {
+					log.Printf("Error removing expired upload %s: %v", id, err)
+				}
+			}
+
+			unlock()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ResendVerificationHandler sends the verification mail again to the unverified user with the given email.
+// Every request counts as an attempt of the ip and the email, and the response is the same whether the user
+// exists or not so the emails can not be enumerated.
+func (s *CommandService) ResendVerificationHandler(c *fiber.Ctx) error {
+	input := new(models.ResendVerificationRequest)
+	if err := c.BodyParser(input); err != nil || input.Email == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.JsonError("Invalid body"))
+	}
+
+	account := accountKey(input.Email)
+	wait, err := s.resends.attempt(c.IP(), account)
+	if err != nil {
+		log.Printf("Error counting resend attempt: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error sending email"))
+	}
+
+	if wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	response := map[string]string{"message": "If the email is registered and not verified a verification mail was sent"}
+	user, err := database.GetUserByEmail(input.Email)
+	if err != nil || user.IsVerified {
+		return c.Status(http.StatusOK).JSON(response)
+	}
+
+	token, err := utils.CreateToken(user.Username, user.ID, models.VerifyToken.String())
+	if err != nil {
+		log.Printf("Error creating token: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error creating token"))
+	}
+
+	err = database.EnqueueMail(&models.OutboxMail{
+		ID:       uuid.NewString(),
+		Type:     models.VerifyToken.String(),
+		Receiver: user.Email,
+		Username: user.Username,
+		Token:    token,
+		Locale:   mailLocale(c),
+	})
+
+	if err != nil {
+		log.Printf("Error queueing email: %v", err)
+		return c.Status(http.StatusInternalServerError).JSON(utils.JsonError("Error sending email"))
+	}
+
+	return c.Status(http.StatusOK).JSON(response)
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DarioRoman01/photos/models"
+)
+
+// ErrInvalidCursor is returned when the cursor of a page can not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// maxPageSize is the maximum number of results of a page.
+const maxPageSize = 50
+
+// cursor is the position of the last result of a page, results are sorted by creation time
+// and then by id so rows created at the same time are neither skipped nor repeated.
+type cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+// encodeCursor returns the opaque representation of the cursor.
+func encodeCursor(createdAt, id string) (string, error) {
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(cursor{CreatedAt: t, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor parses a cursor returned by encodeCursor.
+func decodeCursor(raw string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := new(cursor)
+	if err := json.Unmarshal(data, c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// positionCursor is the position of the last image of a page of an album, the images are sorted by their
+// position in the album and then by id.
+type positionCursor struct {
+	Position int64  `json:"position"`
+	ID       string `json:"id"`
+}
+
+// encodePositionCursor returns the opaque representation of the position cursor.
+func encodePositionCursor(position int64, id string) (string, error) {
+	data, err := json.Marshal(positionCursor{Position: position, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodePositionCursor parses a cursor returned by encodePositionCursor.
+func decodePositionCursor(raw string) (*positionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := new(positionCursor)
+	if err := json.Unmarshal(data, c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// pageSize returns the number of results of the page, at most maxPageSize.
+func pageSize(page *models.PageRequest) int {
+	if page.Limit > maxPageSize || page.Limit < 1 {
+		return maxPageSize
+	}
+
+	return page.Limit
+}
+
+// pageOrder returns the keyset comparison operator and the sql order of the page.
+func pageOrder(page *models.PageRequest) (string, string) {
+	if page.Order == models.Ascending {
+		return ">", "ASC"
+	}
+
+	return "<", "DESC"
+}
+
+// paginate appends the keyset condition, the order and the limit of the page to the query, the query
+// must select from a single table and end with a WHERE clause. It returns the query, its arguments and
+// the page size, one extra row is requested to know if there is a next page.
+func paginate(query string, args []interface{}, page *models.PageRequest) (string, []interface{}, int, error) {
+	limit := pageSize(page)
+	op, order := pageOrder(page)
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return "", nil, 0, err
+		}
+
+		args = append(args, c.CreatedAt, c.ID)
+		query += fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", op, len(args)-1, len(args))
+	}
+
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT $%d", order, order, len(args))
+	return query, args, limit, nil
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the key of the advisory lock held while the migrations run,
+// so only one replica migrates the database at a time.
+const migrationLockKey = 0x70686f746f73
+
+// Migration is a versioned change to the database schema.
+type Migration struct {
+	Version int    // Version is the order of the migration.
+	Name    string // Name describes the migration.
+	Up      string // Up is the SQL that applies the migration.
+	Down    string // Down is the SQL that reverts the migration.
+}
+
+// MigrationStatus is a migration and the time it was applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // AppliedAt is the time the migration was applied, nil if it is pending.
+}
+
+// loadMigrations reads the embedded migrations, the files are named <version>_<name>.<up|down>.sql.
+func loadMigrations() ([]*Migration, error) {
+	files, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		base := strings.TrimSuffix(file.Name(), ".sql")
+		direction := strings.TrimPrefix(path.Ext(base), ".")
+		rawVersion, description, ok := strings.Cut(strings.TrimSuffix(base, path.Ext(base)), "_")
+		version, err := strconv.Atoi(rawVersion)
+		if !ok || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", file.Name())
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: description}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d must have an up and a down file", migration.Version)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies and reverts the embedded migrations, the applied versions are stored in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB      // db is the database to migrate.
+	migrations []*Migration // migrations are the known migrations sorted by version.
+}
+
+// NewMigrator returns a new Migrator for the given database.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// withLock runs fn with a connection that holds the migrations advisory lock
+// and after the schema_migrations table is created.
+func (m *Migrator) withLock(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return err
+	}
+
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		);
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	return fn(ctx, conn)
+}
+
+// applied returns the applied migrations versions and the time they were applied.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// run executes the given SQL and records the change in schema_migrations in the same transaction.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration *Migration, query, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Up applies the pending migrations in order and returns the applied ones,
+// at most steps migrations are applied unless steps is zero.
+func (m *Migrator) Up(steps int) ([]*Migration, error) {
+	done := []*Migration{}
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if steps > 0 && len(done) == steps {
+				break
+			}
+
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err := m.run(
+				ctx, conn, migration, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name,
+			)
+
+			if err != nil {
+				return err
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down reverts the applied migrations from the newest to the oldest and returns the reverted ones,
+// at most steps migrations are reverted unless steps is zero.
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	done := []*Migration{}
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if steps > 0 && len(done) == steps {
+				break
+			}
+
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			err := m.run(
+				ctx, conn, migration, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", migration.Version,
+			)
+
+			if err != nil {
+				return err
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Status returns every known migration and the time it was applied.
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	status := []*MigrationStatus{}
+	err := m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			s := &MigrationStatus{Migration: *migration}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				s.AppliedAt = &appliedAt
+			}
+
+			status = append(status, s)
+		}
+
+		return nil
+	})
+
+	return status, err
+}
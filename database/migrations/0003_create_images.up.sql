@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS images (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    folder_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    url VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS thumbnail_url VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE images ADD COLUMN IF NOT EXISTS preview_url VARCHAR(255) NOT NULL DEFAULT '';
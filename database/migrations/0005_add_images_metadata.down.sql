@@ -0,0 +1,13 @@
+ALTER TABLE images
+    DROP COLUMN IF EXISTS taken_at,
+    DROP COLUMN IF EXISTS camera_make,
+    DROP COLUMN IF EXISTS camera_model,
+    DROP COLUMN IF EXISTS lens,
+    DROP COLUMN IF EXISTS exposure_time,
+    DROP COLUMN IF EXISTS f_number,
+    DROP COLUMN IF EXISTS iso,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS orientation,
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude;
@@ -0,0 +1,12 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS taken_at TIMESTAMP;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS camera_make VARCHAR(255);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS camera_model VARCHAR(255);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS lens VARCHAR(255);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS exposure_time VARCHAR(32);
+ALTER TABLE images ADD COLUMN IF NOT EXISTS f_number DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS iso INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS width INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS height INTEGER;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS orientation SMALLINT;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE images ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
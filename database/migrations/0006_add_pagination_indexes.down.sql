@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS images_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS images_folder_id_created_at_id_idx;
+DROP INDEX IF EXISTS folders_user_id_created_at_id_idx;
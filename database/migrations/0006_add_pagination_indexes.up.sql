@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS images_user_id_created_at_id_idx ON images (user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS images_folder_id_created_at_id_idx ON images (folder_id, created_at, id);
+CREATE INDEX IF NOT EXISTS folders_user_id_created_at_id_idx ON folders (user_id, created_at, id);
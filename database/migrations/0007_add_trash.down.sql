@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS images_deleted_at_idx;
+DROP INDEX IF EXISTS folders_deleted_at_idx;
+ALTER TABLE images DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE folders DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS images_deleted_at_idx ON images (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS folders_deleted_at_idx ON folders (deleted_at) WHERE deleted_at IS NOT NULL;
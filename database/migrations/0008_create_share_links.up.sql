@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS share_links (
+    id VARCHAR(36) PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    user_id VARCHAR(255) NOT NULL,
+    image_id VARCHAR(255),
+    folder_id VARCHAR(255),
+    password VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    views BIGINT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE CASCADE,
+    CHECK ((image_id IS NULL) <> (folder_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS share_links_user_id_created_at_id_idx ON share_links (user_id, created_at, id);
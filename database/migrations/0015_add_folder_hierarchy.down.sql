@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS folders_user_id_path_idx;
+DROP INDEX IF EXISTS folders_parent_id_idx;
+ALTER TABLE folders DROP COLUMN IF EXISTS path;
+ALTER TABLE folders DROP COLUMN IF EXISTS parent_id;
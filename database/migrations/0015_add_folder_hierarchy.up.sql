@@ -0,0 +1,6 @@
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS parent_id VARCHAR(255) REFERENCES folders (id);
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS path TEXT;
+UPDATE folders SET path = name WHERE path IS NULL;
+ALTER TABLE folders ALTER COLUMN path SET NOT NULL;
+CREATE INDEX IF NOT EXISTS folders_parent_id_idx ON folders (parent_id);
+CREATE INDEX IF NOT EXISTS folders_user_id_path_idx ON folders (user_id, path);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS folder_relocations (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    folder_id VARCHAR(255) NOT NULL,
+    parent_id VARCHAR(255),
+    name VARCHAR(255) NOT NULL,
+    old_path TEXT NOT NULL,
+    new_path TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'copying',
+    lease_id VARCHAR(255) NOT NULL,
+    lease_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS folder_relocations_user_id_idx ON folder_relocations (user_id);
+CREATE INDEX IF NOT EXISTS folder_relocations_lease_until_idx ON folder_relocations (lease_until);
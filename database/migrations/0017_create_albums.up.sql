@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS albums (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    cover_image_id VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (cover_image_id) REFERENCES images (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS albums_user_id_created_at_id_idx ON albums (user_id, created_at, id);
+
+CREATE TABLE IF NOT EXISTS album_images (
+    album_id VARCHAR(255) NOT NULL,
+    image_id VARCHAR(255) NOT NULL,
+    position BIGINT NOT NULL,
+    added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (album_id, image_id),
+    FOREIGN KEY (album_id) REFERENCES albums (id) ON DELETE CASCADE,
+    FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS album_images_album_id_position_idx ON album_images (album_id, position, image_id);
+CREATE INDEX IF NOT EXISTS album_images_image_id_idx ON album_images (image_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS images_preview_url_idx;
+DROP INDEX IF EXISTS images_thumbnail_url_idx;
+DROP INDEX IF EXISTS images_url_idx;
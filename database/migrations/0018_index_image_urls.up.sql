@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS images_url_idx ON images (url);
+CREATE INDEX IF NOT EXISTS images_thumbnail_url_idx ON images (thumbnail_url);
+CREATE INDEX IF NOT EXISTS images_preview_url_idx ON images (preview_url);
@@ -0,0 +1,4 @@
+ALTER TABLE images ALTER COLUMN preview_url TYPE VARCHAR(255);
+ALTER TABLE images ALTER COLUMN thumbnail_url TYPE VARCHAR(255);
+ALTER TABLE images ALTER COLUMN url TYPE VARCHAR(255);
+ALTER TABLE images DROP COLUMN IF EXISTS object_name;
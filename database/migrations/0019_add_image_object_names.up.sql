@@ -0,0 +1,6 @@
+ALTER TABLE images ADD COLUMN IF NOT EXISTS object_name TEXT;
+UPDATE images SET object_name = name WHERE object_name IS NULL;
+ALTER TABLE images ALTER COLUMN object_name SET NOT NULL;
+ALTER TABLE images ALTER COLUMN url TYPE TEXT;
+ALTER TABLE images ALTER COLUMN thumbnail_url TYPE TEXT;
+ALTER TABLE images ALTER COLUMN preview_url TYPE TEXT;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS folder_relocation_keys (
+    relocation_id VARCHAR(255) NOT NULL,
+    key TEXT NOT NULL,
+    PRIMARY KEY (relocation_id, key),
+    FOREIGN KEY (relocation_id) REFERENCES folder_relocations (id) ON DELETE CASCADE
+);
@@ -2,12 +2,14 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/DarioRoman01/photos/models"
 	"github.com/DarioRoman01/photos/utils"
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresRepository is a repository that uses a Postgres database.
@@ -26,65 +28,90 @@ func NewPostgresRepository(url string) (*PostgresRepository, error) {
 		return nil, err
 	}
 
-	repo := &PostgresRepository{db: db}
-	repo.Migrate()
-	return repo, nil
+	return &PostgresRepository{db: db}, nil
 }
 
-// Migrate the database to the latest schema version.
-func (r *PostgresRepository) Migrate() {
-	_, err := r.db.Exec(`
-		CREATE TABLE IF NOT EXISTS users (
-			id VARCHAR(255) PRIMARY KEY,
-			username VARCHAR(255) NOT NULL,
-			email VARCHAR(255) NOT NULL,
-			password VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			is_verified BOOLEAN NOT NULL DEFAULT FALSE
-		);
-	`)
+// Migrator returns a Migrator for the repository database.
+func (r *PostgresRepository) Migrator() (*Migrator, error) {
+	return NewMigrator(r.db)
+}
+
+// imageColumns are the columns selected for every image query, in the order expected by scanImage.
+const imageColumns = `id, name, object_name, url, thumbnail_url, preview_url, user_id, folder_id, created_at, deleted_at,
+	taken_at, camera_make, camera_model, lens, exposure_time, f_number, iso, width, height, orientation, latitude, longitude`
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is implemented by *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// querier is implemented by sql.DB and sql.Tx, so the queries can run inside a transaction or not.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanImage scans an image selected with imageColumns.
+func scanImage(row scanner) (*models.Image, error) {
+	image := &models.Image{}
+	m := &image.Metadata
+	err := row.Scan(
+		&image.ID, &image.Name, &image.ObjectName, &image.URL, &image.ThumbnailURL, &image.PreviewURL,
+		&image.UserID, &image.FolderID, &image.CreatedAt, &image.DeletedAt,
+		&m.TakenAt, &m.CameraMake, &m.CameraModel, &m.Lens, &m.ExposureTime, &m.FNumber,
+		&m.ISO, &m.Width, &m.Height, &m.Orientation, &m.Latitude, &m.Longitude,
+	)
 
 	if err != nil {
-		log.Fatalf("Error creating users table: %v", err)
+		return nil, err
 	}
 
-	_, err = r.db.Exec(`
-		CREATE TABLE IF NOT EXISTS folders (
-			id VARCHAR(255) PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			user_id VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
-		);
-	`)
+	return image, nil
+}
+
+// folderColumns are the columns selected for every folder query, in the order expected by scanFolder.
+const folderColumns = "id, name, parent_id, path, user_id, created_at, deleted_at"
 
+// scanFolder scans a folder selected with folderColumns.
+func scanFolder(row scanner) (*models.Folder, error) {
+	folder := &models.Folder{}
+	err := row.Scan(&folder.ID, &folder.Name, &folder.ParentID, &folder.Path, &folder.UserID, &folder.CreatedAt, &folder.DeletedAt)
 	if err != nil {
-		log.Fatalf("Error creating folders table: %v", err)
+		return nil, err
 	}
 
-	_, err = r.db.Exec(`
-		CREATE TABLE IF NOT EXISTS images (
-			id VARCHAR(255) PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			folder_id VARCHAR(36) NOT NULL,
-			user_id VARCHAR(36) NOT NULL,
-			url VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-	`)
+	return folder, nil
+}
 
+// affectedOne returns ErrNotFound if the statement did not change any row.
+func affectedOne(res sql.Result) error {
+	n, err := res.RowsAffected()
 	if err != nil {
-		log.Fatalf("Error creating images table: %v", err)
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
 	}
+
+	return nil
 }
 
 // InsertImage inserts an image into the database.
 func (r *PostgresRepository) InsertImage(image *models.Image) error {
-	_, err := r.db.Exec(
-		"INSERT INTO images (id, name, url, user_id, folder_id) VALUES ($1, $2, $3, $4, $5)",
-		image.ID, image.Name, image.URL, image.UserID, image.FolderID,
+	m := image.Metadata
+	_, err := r.db.Exec(`
+		INSERT INTO images (
+			id, name, object_name, url, thumbnail_url, preview_url, user_id, folder_id,
+			taken_at, camera_make, camera_model, lens, exposure_time, f_number, iso, width, height, orientation, latitude, longitude
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+		image.ID, image.Name, image.ObjectName, image.URL, image.ThumbnailURL, image.PreviewURL, image.UserID, image.FolderID,
+		m.TakenAt, m.CameraMake, m.CameraModel, m.Lens, m.ExposureTime, m.FNumber, m.ISO, m.Width, m.Height, m.Orientation, m.Latitude, m.Longitude,
 	)
 
 	return err
@@ -95,34 +122,254 @@ func (r *PostgresRepository) UpdateUserStatus(id string) error {
 	return err
 }
 
-// InsertFolder inserts a folder into the database.
-func (r *PostgresRepository) InsertFolder(folder *models.Folder) error {
-	_, err := r.db.Exec(
-		"INSERT INTO folders (id, name, user_id) VALUES ($1, $2, $3)",
-		folder.ID, folder.Name, folder.UserID,
+// UpdateUserPassword replaces the password hash of the user with the given id if it is still the current one,
+// so concurrent changes made from the same state can not both succeed.
+func (r *PostgresRepository) UpdateUserPassword(id, current, password string) error {
+	res, err := r.db.Exec("UPDATE users SET password = $1 WHERE id = $2 AND password = $3", password, id, current)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// SetTOTPSecret stores the secret of a TOTP enrolment, it is not required at login until it is confirmed.
+func (r *PostgresRepository) SetTOTPSecret(userID, secret string) error {
+	res, err := r.db.Exec(
+		"UPDATE users SET totp_secret = $1, totp_last_step = 0 WHERE id = $2 AND totp_enabled = FALSE",
+		secret, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// EnableTOTP requires the TOTP code at login for the given user and replaces its recovery codes.
+func (r *PostgresRepository) EnableTOTP(userID string, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	res, err := tx.Exec("UPDATE users SET totp_enabled = TRUE WHERE id = $1 AND totp_secret <> ''", userID)
+	if err != nil {
+		return err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (code_hash, user_id) VALUES ($1, $2)", hash, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP removes the TOTP secret and the recovery codes of the given user.
+func (r *PostgresRepository) DisableTOTP(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	_, err = tx.Exec("UPDATE users SET totp_secret = '', totp_enabled = FALSE, totp_last_step = 0 WHERE id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records the time step of the last accepted TOTP code, ErrNotFound is returned
+// if the step is not newer than the last one so a code can not be replayed.
+func (r *PostgresRepository) UseTOTPStep(userID string, step int64) error {
+	res, err := r.db.Exec("UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1", step, userID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// UseRecoveryCode marks the recovery code with the given hash as used, ErrNotFound is returned
+// if the code does not exist or was already used.
+func (r *PostgresRepository) UseRecoveryCode(userID, codeHash string) error {
+	res, err := r.db.Exec(
+		"UPDATE recovery_codes SET used_at = NOW() WHERE code_hash = $1 AND user_id = $2 AND used_at IS NULL",
+		codeHash, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// folderLockClass is the first key of the advisory locks taken to change the folders of a user,
+// the second key is the hash of the user id.
+const folderLockClass = 0x666f6c64
+
+// lockFolders serializes the changes of the folders of the user until the end of the transaction,
+// so concurrent requests do not create two folders with the same path or move a folder into itself.
+func lockFolders(tx *sql.Tx, userID string) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock($1, hashtext($2))", folderLockClass, userID)
+	return err
+}
+
+// checkRelocations returns ErrRelocationInProgress if a folder of the user is being relocated, the objects of the
+// folders and images of the user must not be changed until the relocation finishes.
+func checkRelocations(db querier, userID string) error {
+	var inProgress bool
+	row := db.QueryRow("SELECT EXISTS (SELECT 1 FROM folder_relocations WHERE user_id = $1)", userID)
+	if err := row.Scan(&inProgress); err != nil {
+		return err
+	}
+
+	if inProgress {
+		return ErrRelocationInProgress
+	}
+
+	return nil
+}
+
+// findFolder returns the folder of the user with the given path, folders in the trash are ignored.
+func findFolder(tx *sql.Tx, userID, path string) (*models.Folder, error) {
+	row := tx.QueryRow(
+		"SELECT "+folderColumns+" FROM folders WHERE user_id = $1 AND path = $2 AND deleted_at IS NULL ORDER BY created_at LIMIT 1",
+		userID, path,
+	)
+
+	return scanFolder(row)
+}
+
+// userFolder returns the folder with the given id if it belongs to the user and is not in the trash.
+func userFolder(tx *sql.Tx, id, userID string) (*models.Folder, error) {
+	row := tx.QueryRow("SELECT "+folderColumns+" FROM folders WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL", id, userID)
+	folder, err := scanFolder(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return folder, err
+}
+
+// insertFolder inserts a folder with its parent and path already set.
+func insertFolder(db execer, folder *models.Folder) error {
+	_, err := db.Exec(
+		"INSERT INTO folders (id, name, parent_id, path, user_id) VALUES ($1, $2, $3, $4, $5)",
+		folder.ID, folder.Name, folder.ParentID, folder.Path, folder.UserID,
 	)
 
 	return err
 }
 
-// CheckFolder checks if the folder exists if not exists its create a new folder with the given name
-func (r *PostgresRepository) CheckFolder(userID, foldeName string) (string, error) {
-	row := r.db.QueryRow("SELECT id FROM folders WHERE name = $1 AND user_id = $2", foldeName, userID)
-	var id string
-	err := row.Scan(&id)
+// InsertFolder inserts a folder into its parent folder, or into the root if it has no parent, and sets its path.
+// ErrNotFound is returned if the parent does not exist and ErrFolderExists if the path is already used.
+func (r *PostgresRepository) InsertFolder(folder *models.Folder) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		id = uuid.NewString()
-		_, err := r.db.Exec(
-			"INSERT INTO folders (id, name, user_id) VALUES ($1, $2, $3)",
-			id, foldeName, userID,
-		)
+		return err
+	}
+
+	defer tx.Rollback()
+	if err := lockFolders(tx, folder.UserID); err != nil {
+		return err
+	}
+
+	folder.Path = folder.Name
+	if folder.ParentID != nil {
+		parent, err := userFolder(tx, *folder.ParentID, folder.UserID)
+		if err != nil {
+			return err
+		}
+
+		folder.Path = parent.Path + "/" + folder.Name
+	}
+
+	if _, err := findFolder(tx, folder.UserID, folder.Path); err != sql.ErrNoRows {
+		if err == nil {
+			return ErrFolderExists
+		}
+
+		return err
+	}
+
+	if err := insertFolder(tx, folder); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CheckFolder returns the id of the folder with the given path, like a/b/c, creating the missing
+// folders along the way. Folders in the trash are ignored.
+func (r *PostgresRepository) CheckFolder(userID, path string) (string, error) {
+	names, err := utils.SplitFolderPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+	if err := lockFolders(tx, userID); err != nil {
+		return "", err
+	}
+
+	folderID, err := checkFolder(tx, userID, names)
+	if err != nil {
+		return "", err
+	}
+
+	return folderID, tx.Commit()
+}
+
+// checkFolder returns the id of the folder with the path of the given names, creating the missing folders.
+// The folders of the user must be locked.
+func checkFolder(tx *sql.Tx, userID string, names []string) (string, error) {
+	var parentID *string
+	path := ""
+	for _, name := range names {
+		if parentID != nil {
+			path += "/"
+		}
+
+		path += name
+		folder, err := findFolder(tx, userID, path)
+		if err == sql.ErrNoRows {
+			folder = &models.Folder{ID: uuid.NewString(), Name: name, ParentID: parentID, Path: path, UserID: userID}
+			err = insertFolder(tx, folder)
+		}
 
 		if err != nil {
 			return "", err
 		}
+
+		parentID = &folder.ID
 	}
 
-	return id, nil
+	return *parentID, nil
 }
 
 // InsertUser inserts a user into the database.
@@ -135,23 +382,38 @@ func (r *PostgresRepository) InsertUser(user *models.User) error {
 	return err
 }
 
-// GetUserByEmail returns a user with the given email.
-func (r *PostgresRepository) GetUserByEmail(email string) (*models.User, error) {
-	row := r.db.QueryRow("SELECT id, username, email, password, is_verified FROM users WHERE email = $1", email)
-	user := &models.User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.IsVerified)
+// InsertUserWithMail inserts a user and adds a mail to the outbox in the same transaction,
+// so the mail is sent if and only if the user is created.
+func (r *PostgresRepository) InsertUserWithMail(user *models.User, mail *models.OutboxMail) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return user, nil
+	defer tx.Rollback()
+	_, err = tx.Exec(
+		"INSERT INTO users (id, username, email, password) VALUES ($1, $2, $3, $4)",
+		user.ID, user.Username, user.Email, user.Password,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := enqueueMail(tx, mail); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// GetUserByID returns a user with the given id.
-func (r *PostgresRepository) GetUserByID(id string) (*models.User, error) {
-	row := r.db.QueryRow("SELECT id, username, email, password, is_verified FROM users WHERE id = $1", id)
+// userColumns are the columns selected for every user query, in the order expected by scanUser.
+const userColumns = "id, username, email, password, is_verified, totp_secret, totp_enabled"
+
+// scanUser scans a user selected with userColumns.
+func scanUser(row scanner) (*models.User, error) {
 	user := &models.User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.IsVerified)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.IsVerified, &user.TOTPSecret, &user.TOTPEnabled)
 	if err != nil {
 		return nil, err
 	}
@@ -159,222 +421,1862 @@ func (r *PostgresRepository) GetUserByID(id string) (*models.User, error) {
 	return user, nil
 }
 
+// GetUserByEmail returns a user with the given email.
+func (r *PostgresRepository) GetUserByEmail(email string) (*models.User, error) {
+	row := r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE email = $1", email)
+	return scanUser(row)
+}
+
+// GetUserByID returns a user with the given id.
+func (r *PostgresRepository) GetUserByID(id string) (*models.User, error) {
+	row := r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", id)
+	return scanUser(row)
+}
+
 // GetUserByUsername returns a user with the given username.
 func (r *PostgresRepository) GetUserByUsername(username string) (*models.User, error) {
-	row := r.db.QueryRow("SELECT id, username, email, password, is_verified FROM users WHERE username = $1", username)
-	user := &models.User{}
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.IsVerified)
-	if err != nil {
-		return nil, err
-	}
+	row := r.db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = $1", username)
+	return scanUser(row)
+}
 
-	return user, nil
+// GetUserByIdentity returns the user linked to the identity with the given issuer and subject.
+func (r *PostgresRepository) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	row := r.db.QueryRow(
+		"SELECT "+userColumns+" FROM users WHERE id = (SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2)",
+		issuer, subject,
+	)
+
+	return scanUser(row)
+}
+
+// InsertIdentity links an identity of an external identity provider to a user.
+func (r *PostgresRepository) InsertIdentity(identity *models.Identity) error {
+	_, err := r.db.Exec(
+		"INSERT INTO user_identities (issuer, subject, user_id, email) VALUES ($1, $2, $3, $4)",
+		identity.Issuer, identity.Subject, identity.UserID, identity.Email,
+	)
+
+	return err
 }
 
 // GetImage returns an image with the given id.
 func (r *PostgresRepository) GetImage(id string) (*models.Image, error) {
-	row := r.db.QueryRow("SELECT id, name, url, user_id, folder_id, created_at FROM images WHERE id = $1", id)
-	image := &models.Image{}
-	err := row.Scan(&image.ID, &image.Name, &image.URL, &image.UserID, &image.FolderID, &image.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-
-	return image, nil
+	row := r.db.QueryRow("SELECT "+imageColumns+" FROM images WHERE id = $1 AND deleted_at IS NULL", id)
+	return scanImage(row)
 }
 
-// GetImages returns all images for the given user.
-func (r *PostgresRepository) GetImages(userID, cursor string, limit int) ([]*models.Image, error, bool) {
-	if limit > 50 || limit < 1 {
-		limit = 50
-	}
+// GetImageByURL returns the image whose file or one of its variants is stored at the given url,
+// the images in the trash are not returned.
+func (r *PostgresRepository) GetImageByURL(url string) (*models.Image, error) {
+	row := r.db.QueryRow(
+		"SELECT "+imageColumns+" FROM images WHERE (url = $1 OR thumbnail_url = $1 OR preview_url = $1) AND deleted_at IS NULL LIMIT 1",
+		url,
+	)
 
-	var rows *sql.Rows
-	var err error
-	if cursor == "" {
-		rows, err = r.db.Query(`
-			SELECT id, name, url, user_id, folder_id, created_at FROM images WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
-		`, userID, limit)
+	return scanImage(row)
+}
 
-	} else {
-		rows, err = r.db.Query(`
-		"SELECT id, name, url, user_id, folder_id, created_at FROM images WHERE user_id = $1 AND created_at < $2 ORDER BY created_at DESC LIMIT $3"
-		`, userID, cursor, limit)
+// queryImages returns a page of the images selected by the given query and the cursor of the next page.
+func (r *PostgresRepository) queryImages(query string, args []interface{}, page *models.PageRequest) ([]*models.Image, string, error) {
+	query, args, limit, err := paginate(query, args, page)
+	if err != nil {
+		return nil, "", err
 	}
 
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err, false
+		return nil, "", err
 	}
 
 	defer rows.Close()
 	images := []*models.Image{}
 	for rows.Next() {
-		image := &models.Image{}
-		err := rows.Scan(&image.ID, &image.Name, &image.URL, &image.UserID, &image.FolderID, &image.CreatedAt)
+		image, err := scanImage(rows)
 		if err != nil {
-			return nil, err, false
+			return nil, "", err
 		}
 
 		images = append(images, image)
 	}
 
-	if len(images) == 0 {
-		return nil, nil, false
+	if err := rows.Err(); err != nil {
+		return nil, "", err
 	}
 
-	if len(images) == limit {
-		return images, nil, true
+	if len(images) <= limit {
+		return images, "", nil
 	}
 
-	return images, nil, false
+	images = images[:limit]
+	last := images[limit-1]
+	next, err := encodeCursor(last.CreatedAt, last.ID)
+	return images, next, err
 }
 
-// GetImagesByFolder returns all images for the given folder.
-func (r *PostgresRepository) GetImagesByFolder(userID, folderID, cursor string, limit int) ([]*models.Image, bool, error) {
-	if limit > 50 || limit < 1 {
-		limit = 50
-	}
+// GetImages returns a page of the images of the given user.
+func (r *PostgresRepository) GetImages(userID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return r.queryImages(
+		"SELECT "+imageColumns+" FROM images WHERE user_id = $1 AND deleted_at IS NULL",
+		[]interface{}{userID}, page,
+	)
+}
+
+// GetImagesByFolder returns a page of the images of the given folder.
+func (r *PostgresRepository) GetImagesByFolder(userID, folderID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return r.queryImages(
+		"SELECT "+imageColumns+" FROM images WHERE user_id = $1 AND folder_id = $2 AND deleted_at IS NULL",
+		[]interface{}{userID, folderID}, page,
+	)
+}
 
-	var rows *sql.Rows
-	var err error
-	if cursor == "" {
-		rows, err = r.db.Query(`
-			SELECT id, name, url, user_id, folder_id, created_at FROM images WHERE user_id = $1 AND folder_id = $2 ORDER BY created_at DESC LIMIT $3
-		`, userID, folderID, limit)
+// GetFolder returns a folder with the given id.
+func (r *PostgresRepository) GetFolder(id string) (*models.Folder, error) {
+	row := r.db.QueryRow("SELECT "+folderColumns+" FROM folders WHERE id = $1 AND deleted_at IS NULL", id)
+	return scanFolder(row)
+}
 
-	} else {
-		rows, err = r.db.Query(`
-		"SELECT id, name, url, user_id, folder_id, created_at FROM images WHERE user_id = $1 AND folder_id = $2 AND created_at < $3 ORDER BY created_at DESC LIMIT $4"
-		`, userID, folderID, cursor, limit)
+// queryFolders returns a page of the folders selected by the given query and the cursor of the next page.
+func (r *PostgresRepository) queryFolders(query string, args []interface{}, page *models.PageRequest) ([]*models.Folder, string, error) {
+	query, args, limit, err := paginate(query, args, page)
+	if err != nil {
+		return nil, "", err
 	}
 
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, false, err
+		return nil, "", err
 	}
 
 	defer rows.Close()
-	images := []*models.Image{}
+	folders := []*models.Folder{}
 	for rows.Next() {
-		image := &models.Image{}
-		err := rows.Scan(&image.ID, &image.Name, &image.URL, &image.UserID, &image.FolderID)
+		folder, err := scanFolder(rows)
 		if err != nil {
-			return nil, false, err
+			return nil, "", err
 		}
 
-		images = append(images, image)
+		folders = append(folders, folder)
 	}
 
-	if len(images) == 0 {
-		return nil, false, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", err
 	}
 
-	if len(images) == limit {
-		return images, true, nil
+	if len(folders) <= limit {
+		return folders, "", nil
 	}
 
-	return images, false, nil
+	folders = folders[:limit]
+	last := folders[limit-1]
+	next, err := encodeCursor(last.CreatedAt, last.ID)
+	return folders, next, err
 }
 
-// GetFolder returns a folder with the given id.
-func (r *PostgresRepository) GetFolder(id string) (*models.Folder, error) {
-	row := r.db.QueryRow("SELECT id, name, user_id, created_at FROM folders WHERE id = $1", id)
-	folder := &models.Folder{}
-	err := row.Scan(&folder.ID, &folder.Name, &folder.UserID, &folder.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-
-	return folder, nil
+// GetFolders returns a page of the folders of the given user.
+func (r *PostgresRepository) GetFolders(userID string, page *models.PageRequest) ([]*models.Folder, string, error) {
+	return r.queryFolders(
+		"SELECT "+folderColumns+" FROM folders WHERE user_id = $1 AND deleted_at IS NULL",
+		[]interface{}{userID}, page,
+	)
 }
 
-// GetFolders returns all folders for the given user.
-func (r *PostgresRepository) GetFolders(userID, cursor string, limit int) ([]*models.Folder, bool, error) {
-	if limit > 50 || limit < 1 {
-		limit = 50
+// folderSubtree selects into subtree the ids of the folder $1 and of its descendants, trashed or not.
+const folderSubtree = `WITH RECURSIVE subtree AS (
+		SELECT id FROM folders WHERE id = $1
+		UNION ALL
+		SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+	) `
+
+// GetSubfolders returns a page of the folders in the given folder, or of all its descendants if recursive is true.
+func (r *PostgresRepository) GetSubfolders(userID, folderID string, recursive bool, page *models.PageRequest) ([]*models.Folder, string, error) {
+	if !recursive {
+		return r.queryFolders(
+			"SELECT "+folderColumns+" FROM folders WHERE parent_id = $1 AND user_id = $2 AND deleted_at IS NULL",
+			[]interface{}{folderID, userID}, page,
+		)
 	}
 
-	var rows *sql.Rows
-	var err error
-	if cursor == "" {
-		rows, err = r.db.Query(`
-			SELECT id, name, user_id, created_at FROM folders WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
-		`, userID, limit)
+	return r.queryFolders(
+		folderSubtree+"SELECT "+folderColumns+` FROM folders
+		WHERE id IN (SELECT id FROM subtree) AND id <> $1 AND user_id = $2 AND deleted_at IS NULL`,
+		[]interface{}{folderID, userID}, page,
+	)
+}
 
-	} else {
-		rows, err = r.db.Query(`
-		"SELECT id, name, user_id, created_at FROM folders WHERE user_id = $1 AND created_at < $2 ORDER BY created_at DESC LIMIT $3"
-		`, userID, cursor, limit)
-	}
+// GetImagesByFolderTree returns a page of the images of the given folder and of all its descendants.
+func (r *PostgresRepository) GetImagesByFolderTree(userID, folderID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return r.queryImages(
+		folderSubtree+"SELECT "+imageColumns+` FROM images
+		WHERE folder_id IN (SELECT id FROM subtree) AND user_id = $2 AND deleted_at IS NULL`,
+		[]interface{}{folderID, userID}, page,
+	)
+}
+
+// GetFolderBreadcrumbs returns the ancestors of the folder with the given id from the root, followed by the folder.
+func (r *PostgresRepository) GetFolderBreadcrumbs(id string) ([]*models.Folder, error) {
+	rows, err := r.db.Query(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM folders WHERE id = $1
+			UNION ALL
+			SELECT folders.id, folders.parent_id FROM folders JOIN ancestors ON folders.id = ancestors.parent_id
+		)
+		SELECT `+folderColumns+` FROM folders WHERE id IN (SELECT id FROM ancestors) ORDER BY length(path)
+	`, id)
 
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	defer rows.Close()
 	folders := []*models.Folder{}
 	for rows.Next() {
-		folder := &models.Folder{}
-		err := rows.Scan(&folder.ID, &folder.Name, &folder.UserID, &folder.CreatedAt)
+		folder, err := scanFolder(rows)
 		if err != nil {
-			return nil, false, err
+			return nil, err
 		}
 
 		folders = append(folders, folder)
 	}
 
-	if len(folders) == 0 {
-		return nil, false, nil
+	return folders, rows.Err()
+}
+
+// folderDestination returns the parent and the path of the folder once it is moved into the given parent, or into
+// the root if parentID is nil, with the given name. ErrFolderCycle is returned if the parent is the folder or one of
+// its descendants and ErrFolderExists if the new path is used by another folder, even in the trash.
+func folderDestination(tx *sql.Tx, folder *models.Folder, parentID *string, name string) (*string, string, error) {
+	path := name
+	if parentID != nil {
+		var cycle bool
+		row := tx.QueryRow(folderSubtree+"SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)", folder.ID, *parentID)
+		if err := row.Scan(&cycle); err != nil {
+			return nil, "", err
+		}
+
+		if cycle {
+			return nil, "", ErrFolderCycle
+		}
+
+		parent, err := userFolder(tx, *parentID, folder.UserID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		path = parent.Path + "/" + name
 	}
 
-	if len(folders) == limit {
-		return folders, true, nil
+	if path != folder.Path {
+		// the folders in the trash and their descendants also use their paths, a rollback of the relocation
+		// deletes every object under the new path so it must not contain the objects of other folders
+		var used bool
+		row := tx.QueryRow(folderSubtree+`
+			SELECT EXISTS (
+				SELECT 1 FROM folders
+				WHERE user_id = $2 AND (path = $3::text OR left(path, length($3::text) + 1) = $3::text || '/')
+				AND id NOT IN (SELECT id FROM subtree)
+			)`,
+			folder.ID, folder.UserID, path,
+		)
+
+		if err := row.Scan(&used); err != nil {
+			return nil, "", err
+		}
+
+		if used {
+			return nil, "", ErrFolderExists
+		}
 	}
-	return folders, false, nil
+
+	return parentID, path, nil
 }
 
-// GetImageURL returns the url for the given image id.
-func (r *PostgresRepository) getImageUrl(id string) (string, error) {
-	row := r.db.QueryRow("SELECT url FROM images WHERE id = $1", id)
-	var url string
-	err := row.Scan(&url)
+// CreateFolderRelocation validates the relocation of a folder, sets its old and new paths and journals it with a lease
+// of the given duration. Nothing is journaled if the folder keeps its path, and ErrRelocationInProgress is returned
+// if another folder of the user is being relocated.
+func (r *PostgresRepository) CreateFolderRelocation(relocation *models.FolderRelocation, lease time.Duration) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return url, nil
-}
+	defer tx.Rollback()
+	if err := lockFolders(tx, relocation.UserID); err != nil {
+		return err
+	}
 
-// UpdateImage updates the image with the given id only the folder and the url can be chage.
-func (r *PostgresRepository) UpdateImage(req *models.MoveFileRequest, userId string) error {
-	folderId, err := r.CheckFolder(userId, req.NewFolderName)
-	if err != nil {
+	if err := checkRelocations(tx, relocation.UserID); err != nil {
 		return err
 	}
 
-	imgUrl, err := r.getImageUrl(req.FileID)
+	folder, err := userFolder(tx, relocation.FolderID, relocation.UserID)
 	if err != nil {
 		return err
 	}
 
-	newUrl := utils.ChangeUrlPath(imgUrl, req.NewFolderName)
-	_, err = r.db.Exec("UPDATE images SET folder_id = $1, url = $2 WHERE id = $3", folderId, newUrl, req.FileID)
+	_, path, err := folderDestination(tx, folder, relocation.ParentID, relocation.Name)
+	if err != nil {
+		return err
+	}
+
+	relocation.OldPath = folder.Path
+	relocation.NewPath = path
+	relocation.Status = models.RelocationCopying
+	if relocation.OldPath == relocation.NewPath {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO folder_relocations (id, user_id, username, folder_id, parent_id, name, old_path, new_path, status, lease_id, lease_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW() + make_interval(secs => $11))`,
+		relocation.ID, relocation.UserID, relocation.Username, relocation.FolderID, relocation.ParentID, relocation.Name,
+		relocation.OldPath, relocation.NewPath, relocation.Status, relocation.LeaseID, lease.Seconds(),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClaimFolderRelocation takes over a relocation whose lease expired with a new lease of the given duration,
+// ErrNotFound is returned if there is none.
+func (r *PostgresRepository) ClaimFolderRelocation(lease time.Duration) (*models.FolderRelocation, error) {
+	relocation := &models.FolderRelocation{}
+	row := r.db.QueryRow(
+		`UPDATE folder_relocations SET lease_id = $1, lease_until = NOW() + make_interval(secs => $2)
+		WHERE id = (
+			SELECT id FROM folder_relocations WHERE lease_until <= NOW()
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, username, folder_id, parent_id, name, old_path, new_path, status, lease_id`,
+		uuid.NewString(), lease.Seconds(),
+	)
+
+	err := row.Scan(
+		&relocation.ID, &relocation.UserID, &relocation.Username, &relocation.FolderID, &relocation.ParentID,
+		&relocation.Name, &relocation.OldPath, &relocation.NewPath, &relocation.Status, &relocation.LeaseID,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return relocation, nil
+}
+
+// RenewFolderRelocation extends the lease of a relocation, ErrNotFound is returned if the lease was taken over.
+func (r *PostgresRepository) RenewFolderRelocation(relocation *models.FolderRelocation, lease time.Duration) error {
+	res, err := r.db.Exec(
+		"UPDATE folder_relocations SET lease_until = NOW() + make_interval(secs => $1) WHERE id = $2 AND lease_id = $3",
+		lease.Seconds(), relocation.ID, relocation.LeaseID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// CommitFolderRelocation moves the folder of a relocation whose objects were copied, in the same transaction it
+// updates the paths of the folder and its descendants, points the urls of their images, built with objectURL,
+// to the new objects, journals the old keys of those objects and marks the relocation as committed. ErrNotFound
+// is returned if the lease was taken over.
+func (r *PostgresRepository) CommitFolderRelocation(relocation *models.FolderRelocation, objectURL func(key string) string) (*models.Folder, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+	if err := lockFolders(tx, relocation.UserID); err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(
+		"UPDATE folder_relocations SET status = $1 WHERE id = $2 AND lease_id = $3 AND status = $4",
+		models.RelocationCommitted, relocation.ID, relocation.LeaseID, models.RelocationCopying,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return nil, err
+	}
+
+	folder, err := userFolder(tx, relocation.FolderID, relocation.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the folders of the user can not be relocated meanwhile, so the destination is only checked again
+	parent, path, err := folderDestination(tx, folder, relocation.ParentID, relocation.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if folder.Path != relocation.OldPath || path != relocation.NewPath {
+		return nil, ErrFolderExists
+	}
+
+	// the images are read before the paths change, the keys of their objects under the old path are journaled
+	// so the relocation only moves the objects of the images it committed
+	images, err := subtreeImages(tx, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE folders SET parent_id = $1, name = $2 WHERE id = $3", parent, relocation.Name, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the paths of the descendants start with the path of the folder, only that prefix changes
+	_, err = tx.Exec(
+		folderSubtree+"UPDATE folders SET path = $2::text || substr(path, length($3::text) + 1) WHERE id IN (SELECT id FROM subtree)",
+		folder.ID, path, folder.Path,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		newFolder := path + strings.TrimPrefix(image.FolderName, folder.Path)
+		key := func(folderPath, name string) string {
+			return relocation.Username + "/" + folderPath + "/" + name
+		}
+
+		names := []string{image.ObjectName}
+		image.URL = objectURL(key(newFolder, image.ObjectName))
+		if image.ThumbnailURL != "" {
+			name := utils.VariantFilename(image.ObjectName, models.ThumbnailVariant)
+			image.ThumbnailURL = objectURL(key(newFolder, name))
+			names = append(names, name)
+		}
+
+		if image.PreviewURL != "" {
+			name := utils.VariantFilename(image.ObjectName, models.PreviewVariant)
+			image.PreviewURL = objectURL(key(newFolder, name))
+			names = append(names, name)
+		}
+
+		_, err := tx.Exec(
+			"UPDATE images SET url = $1, thumbnail_url = $2, preview_url = $3 WHERE id = $4",
+			image.URL, image.ThumbnailURL, image.PreviewURL, image.ID,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			_, err := tx.Exec(
+				"INSERT INTO folder_relocation_keys (relocation_id, key) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				relocation.ID, key(image.FolderName, name),
+			)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	folder.ParentID = parent
+	folder.Name = relocation.Name
+	folder.Path = path
+	return folder, nil
+}
+
+// subtreeImages returns the images, trashed or not, of the folder with the given id and its descendants
+// with the path of their folder in FolderName.
+func subtreeImages(db querier, folderID string) ([]*models.TrashedImage, error) {
+	rows, err := db.Query(folderSubtree+`
+		SELECT images.id, images.name, images.object_name, images.thumbnail_url, images.preview_url, images.user_id,
+			images.folder_id, images.deleted_at, users.username, folders.path
+		FROM images
+		JOIN users ON users.id = images.user_id
+		JOIN folders ON folders.id = images.folder_id
+		WHERE images.folder_id IN (SELECT id FROM subtree)`,
+		folderID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	images := []*models.TrashedImage{}
+	for rows.Next() {
+		image := &models.TrashedImage{}
+		err := rows.Scan(
+			&image.ID, &image.Name, &image.ObjectName, &image.ThumbnailURL, &image.PreviewURL, &image.UserID,
+			&image.FolderID, &image.DeletedAt, &image.Username, &image.FolderName,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, image)
+	}
+
+	return images, rows.Err()
+}
+
+// AbortFolderRelocation marks a relocation whose folder was not moved as aborted, ErrNotFound is returned
+// if the lease was taken over or the folder was already moved.
+func (r *PostgresRepository) AbortFolderRelocation(relocation *models.FolderRelocation) error {
+	res, err := r.db.Exec(
+		"UPDATE folder_relocations SET status = $1 WHERE id = $2 AND lease_id = $3 AND status IN ($4, $1)",
+		models.RelocationAborted, relocation.ID, relocation.LeaseID, models.RelocationCopying,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// GetFolderTreeImages returns the images, trashed or not, of the folder with the given id and its descendants
+// with the path of their folder in FolderName.
+func (r *PostgresRepository) GetFolderTreeImages(folderID string) ([]*models.TrashedImage, error) {
+	return subtreeImages(r.db, folderID)
+}
+
+// GetFolderRelocationKeys returns the keys under the old path of the objects of the images committed by a relocation.
+func (r *PostgresRepository) GetFolderRelocationKeys(relocationID string) ([]string, error) {
+	rows, err := r.db.Query("SELECT key FROM folder_relocation_keys WHERE relocation_id = $1", relocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteFolderRelocation removes a finished or rolled back relocation from the journal,
+// ErrNotFound is returned if the lease was taken over.
+func (r *PostgresRepository) DeleteFolderRelocation(relocation *models.FolderRelocation) error {
+	res, err := r.db.Exec("DELETE FROM folder_relocations WHERE id = $1 AND lease_id = $2", relocation.ID, relocation.LeaseID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// CheckFolderRelocations returns ErrRelocationInProgress if a folder of the user is being relocated.
+func (r *PostgresRepository) CheckFolderRelocations(userID string) error {
+	return checkRelocations(r.db, userID)
+}
+
+// UpdateImage moves the image with the given id of the given user from the folder with the old path to the folder
+// of the request, creating it if it is missing, and points its urls to the new path. Only the folder and the urls
+// can be changed, and ErrRelocationInProgress is returned if a folder of the user is being relocated.
+func (r *PostgresRepository) UpdateImage(req *models.MoveFileRequest, oldPath, userId string) error {
+	names, err := utils.SplitFolderPath(req.NewFolderName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if err := lockFolders(tx, userId); err != nil {
+		return err
+	}
+
+	if err := checkRelocations(tx, userId); err != nil {
+		return err
+	}
+
+	folderId, err := checkFolder(tx, userId, names)
+	if err != nil {
+		return err
+	}
+
+	image, err := scanImage(tx.QueryRow("SELECT "+imageColumns+" FROM images WHERE id = $1 AND deleted_at IS NULL", req.FileID))
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(
+		"UPDATE images SET folder_id = $1, url = $2, thumbnail_url = $3, preview_url = $4 WHERE id = $5 AND user_id = $6",
+		folderId,
+		utils.ChangeUrlPath(image.URL, oldPath, req.NewFolderName),
+		utils.ChangeUrlPath(image.ThumbnailURL, oldPath, req.NewFolderName),
+		utils.ChangeUrlPath(image.PreviewURL, oldPath, req.NewFolderName),
+		req.FileID,
+		userId,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteFolderTree deletes the folder with the given id, its descendants and their images, trashed or not,
+// and returns the deleted rows. ErrRelocationInProgress is returned if a folder of the user is being relocated.
+func (r *PostgresRepository) DeleteFolderTree(id, userID string) (*models.DeletedFolder, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+	if err := lockFolders(tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := checkRelocations(tx, userID); err != nil {
+		return nil, err
+	}
+
+	folder, err := userFolder(tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := &models.DeletedFolder{Folder: folder}
+	if deleted.Images, err = subtreeImages(tx, id); err != nil {
+		return nil, err
+	}
+
+	// a folder in the trash can have the path of an active folder, its objects share the prefix
+	row := tx.QueryRow(folderSubtree+`
+		SELECT EXISTS (
+			SELECT 1 FROM folders WHERE user_id = $2 AND id NOT IN (SELECT id FROM subtree)
+			AND (path = $3 OR left(path, length($3::text) + 1) = $3::text || '/')
+		)`,
+		id, userID, folder.Path,
+	)
+
+	if err := row.Scan(&deleted.SharedPrefix); err != nil {
+		return nil, err
+	}
+
+	// the images are deleted with their folders by the foreign key
+	res, err := tx.Exec(folderSubtree+"DELETE FROM folders WHERE id IN (SELECT id FROM subtree)", id)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	deleted.Folders = int(n)
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// TrashImage moves the image with the given id to the trash.
+func (r *PostgresRepository) TrashImage(id, userID string) error {
+	res, err := r.db.Exec(
+		"UPDATE images SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL",
+		id, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// TrashFolder moves the folder with the given id, its descendants and their images to the trash, they get
+// the same deleted_at of the folder so they are restored together. ErrRelocationInProgress is returned if a folder
+// of the user is being relocated.
+func (r *PostgresRepository) TrashFolder(id, userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if err := lockFolders(tx, userID); err != nil {
+		return err
+	}
+
+	if err := checkRelocations(tx, userID); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(
+		"UPDATE folders SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL",
+		id, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+
+	// NOW() returns the start time of the transaction, so it matches the folder deleted_at
+	_, err = tx.Exec(folderSubtree+"UPDATE folders SET deleted_at = NOW() WHERE id IN (SELECT id FROM subtree) AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(folderSubtree+"UPDATE images SET deleted_at = NOW() WHERE folder_id IN (SELECT id FROM subtree) AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// restoreAncestors moves the folder with the given id and its ancestors out of the trash,
+// so a restored item is never inside a folder in the trash.
+func restoreAncestors(tx *sql.Tx, folderID string) error {
+	_, err := tx.Exec(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM folders WHERE id = $1
+			UNION ALL
+			SELECT folders.id, folders.parent_id FROM folders JOIN ancestors ON folders.id = ancestors.parent_id
+		)
+		UPDATE folders SET deleted_at = NULL WHERE id IN (SELECT id FROM ancestors) AND deleted_at IS NOT NULL
+	`, folderID)
+
+	return err
+}
+
+// RestoreImage moves the image with the given id out of the trash, its folder and the folder ancestors are
+// restored too if they are in the trash.
+func (r *PostgresRepository) RestoreImage(id, userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	var folderID string
+	row := tx.QueryRow(
+		"UPDATE images SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL RETURNING folder_id",
+		id, userID,
+	)
+
+	if err := row.Scan(&folderID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	if err := restoreAncestors(tx, folderID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RestoreFolder moves the folder with the given id out of the trash with the descendants and the images that
+// were trashed with it, its ancestors are restored too if they are in the trash.
+func (r *PostgresRepository) RestoreFolder(id, userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	_, err = tx.Exec(folderSubtree+`
+		UPDATE images SET deleted_at = NULL FROM folders
+		WHERE folders.id = $1 AND folders.user_id = $2 AND images.folder_id IN (SELECT id FROM subtree)
+		AND images.deleted_at = folders.deleted_at
+	`, id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	// the folder is restored last because its deleted_at tells which descendants were trashed with it
+	_, err = tx.Exec(folderSubtree+`
+		UPDATE folders AS descendants SET deleted_at = NULL FROM folders
+		WHERE folders.id = $1 AND folders.user_id = $2 AND descendants.id IN (SELECT id FROM subtree)
+		AND descendants.id <> $1 AND descendants.deleted_at = folders.deleted_at
+	`, id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(
+		"UPDATE folders SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL",
+		id, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+
+	if err := restoreAncestors(tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTrashedImages returns a page of the images in the trash of the given user.
+func (r *PostgresRepository) GetTrashedImages(userID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return r.queryImages(
+		"SELECT "+imageColumns+" FROM images WHERE user_id = $1 AND deleted_at IS NOT NULL",
+		[]interface{}{userID}, page,
+	)
+}
+
+// GetTrashedFolders returns a page of the folders in the trash of the given user, the descendants trashed
+// with a folder are not listed.
+func (r *PostgresRepository) GetTrashedFolders(userID string, page *models.PageRequest) ([]*models.Folder, string, error) {
+	return r.queryFolders(
+		"SELECT "+folderColumns+` FROM folders WHERE user_id = $1 AND deleted_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM folders AS parent WHERE parent.id = folders.parent_id AND parent.deleted_at = folders.deleted_at)`,
+		[]interface{}{userID}, page,
+	)
+}
+
+// GetPurgeableImages returns at most limit images that are in the trash for at least the given time,
+// if userID is empty the images of every user are returned.
+func (r *PostgresRepository) GetPurgeableImages(userID string, age time.Duration, limit int) ([]*models.TrashedImage, error) {
+	query := `
+		SELECT images.id, images.name, images.object_name, images.thumbnail_url, images.preview_url, images.user_id,
+			images.folder_id, images.deleted_at, users.username, folders.path
+		FROM images
+		JOIN users ON users.id = images.user_id
+		JOIN folders ON folders.id = images.folder_id
+		WHERE images.deleted_at <= NOW() - make_interval(secs => $1)`
+
+	args := []interface{}{age.Seconds(), limit}
+	if userID != "" {
+		args = append(args, userID)
+		query += " AND images.user_id = $3"
+	}
+
+	rows, err := r.db.Query(query+" LIMIT $2", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	images := []*models.TrashedImage{}
+	for rows.Next() {
+		image := &models.TrashedImage{}
+		err := rows.Scan(
+			&image.ID, &image.Name, &image.ObjectName, &image.ThumbnailURL, &image.PreviewURL, &image.UserID,
+			&image.FolderID, &image.DeletedAt, &image.Username, &image.FolderName,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, image)
+	}
+
+	return images, rows.Err()
+}
+
+// PurgeFolders deletes the folders that are in the trash for at least the given time and have no images or folders left,
+// if userID is empty the folders of every user are deleted.
+func (r *PostgresRepository) PurgeFolders(userID string, age time.Duration) error {
+	query := `
+		DELETE FROM folders
+		WHERE deleted_at <= NOW() - make_interval(secs => $1)
+		AND NOT EXISTS (SELECT 1 FROM images WHERE images.folder_id = folders.id)
+		AND NOT EXISTS (SELECT 1 FROM folders AS children WHERE children.parent_id = folders.id)`
+
+	args := []interface{}{age.Seconds()}
+	if userID != "" {
+		args = append(args, userID)
+		query += " AND user_id = $2"
+	}
+
+	// a folder is deleted once its children are, so the trees are deleted from the leaves up
+	for {
+		res, err := r.db.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+	}
+}
+
+// shareLinkColumns are the columns selected for every share link query, in the order expected by scanShareLink.
+const shareLinkColumns = "id, token, user_id, image_id, folder_id, password, expires_at, views, revoked_at, created_at"
+
+// scanShareLink scans a share link selected with shareLinkColumns.
+func scanShareLink(row scanner) (*models.ShareLink, error) {
+	link := &models.ShareLink{}
+	err := row.Scan(
+		&link.ID, &link.Token, &link.UserID, &link.ImageID, &link.FolderID, &link.Password,
+		&link.ExpiresAt, &link.Views, &link.RevokedAt, &link.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	link.HasPassword = link.Password != ""
+	return link, nil
+}
+
+// InsertShareLink inserts a share link into the database.
+func (r *PostgresRepository) InsertShareLink(link *models.ShareLink) error {
+	_, err := r.db.Exec(`
+		INSERT INTO share_links (id, token, user_id, image_id, folder_id, password, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		link.ID, link.Token, link.UserID, link.ImageID, link.FolderID, link.Password, link.ExpiresAt,
+	)
+
+	return err
+}
+
+// GetShareLinkByToken returns the share link with the given token.
+func (r *PostgresRepository) GetShareLinkByToken(token string) (*models.ShareLink, error) {
+	row := r.db.QueryRow("SELECT "+shareLinkColumns+" FROM share_links WHERE token = $1", token)
+	link, err := scanShareLink(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return link, err
+}
+
+// GetShareLinks returns a page of the share links of the given user.
+func (r *PostgresRepository) GetShareLinks(userID string, page *models.PageRequest) ([]*models.ShareLink, string, error) {
+	query, args, limit, err := paginate(
+		"SELECT "+shareLinkColumns+" FROM share_links WHERE user_id = $1",
+		[]interface{}{userID}, page,
+	)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer rows.Close()
+	links := []*models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, "", err
+		}
+
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(links) <= limit {
+		return links, "", nil
+	}
+
+	links = links[:limit]
+	last := links[limit-1]
+	next, err := encodeCursor(last.CreatedAt, last.ID)
+	return links, next, err
+}
+
+// IncrementShareLinkViews adds a view to the share link with the given id.
+func (r *PostgresRepository) IncrementShareLinkViews(id string) error {
+	_, err := r.db.Exec("UPDATE share_links SET views = views + 1 WHERE id = $1", id)
+	return err
+}
+
+// RevokeShareLink revokes the share link with the given id.
+func (r *PostgresRepository) RevokeShareLink(id, userID string) error {
+	res, err := r.db.Exec(
+		"UPDATE share_links SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// InsertSession inserts a session and its first refresh token, the token expires after the given time.
+func (r *PostgresRepository) InsertSession(session *models.Session, refreshTokenHash string, ttl time.Duration) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	_, err = tx.Exec(
+		"INSERT INTO sessions (id, user_id, device, ip) VALUES ($1, $2, $3, $4)",
+		session.ID, session.UserID, session.Device, session.IP,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, NOW() + make_interval(secs => $3))",
+		refreshTokenHash, session.ID, ttl.Seconds(),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RotateRefreshToken marks the refresh token as used and stores the new one in the same session. If the token
+// was already used the session is revoked and ErrTokenReused is returned, if the token is unknown, expired
+// or its session is revoked ErrNotFound is returned.
+func (r *PostgresRepository) RotateRefreshToken(oldHash, newHash string, ttl time.Duration) (*models.Session, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+	var sessionID string
+	var usedAt *time.Time
+	row := tx.QueryRow(
+		"SELECT session_id, used_at FROM refresh_tokens WHERE token_hash = $1 AND expires_at > NOW() FOR UPDATE",
+		oldHash,
+	)
+
+	if err := row.Scan(&sessionID, &usedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	if usedAt != nil {
+		if _, err := tx.Exec("UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", sessionID); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrTokenReused
+	}
+
+	session := &models.Session{}
+	row = tx.QueryRow(`
+		UPDATE sessions SET last_used_at = NOW() FROM users
+		WHERE sessions.id = $1 AND sessions.revoked_at IS NULL AND users.id = sessions.user_id
+		RETURNING sessions.id, sessions.user_id, users.username, sessions.device, sessions.ip, sessions.created_at, sessions.last_used_at
+	`, sessionID)
+
+	err = row.Scan(&session.ID, &session.UserID, &session.Username, &session.Device, &session.IP, &session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = $1", oldHash); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO refresh_tokens (token_hash, session_id, expires_at) VALUES ($1, $2, NOW() + make_interval(secs => $3))",
+		newHash, sessionID, ttl.Seconds(),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return session, tx.Commit()
+}
+
+// IsSessionActive checks if the session with the given id exists and is not revoked.
+func (r *PostgresRepository) IsSessionActive(id string) (bool, error) {
+	var active bool
+	row := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND revoked_at IS NULL)", id)
+	err := row.Scan(&active)
+	return active, err
+}
+
+// GetSessions returns the sessions of the given user that are not revoked and have an unexpired refresh token.
+func (r *PostgresRepository) GetSessions(userID string) ([]*models.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, device, ip, created_at, last_used_at FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE refresh_tokens.session_id = sessions.id AND used_at IS NULL AND expires_at > NOW()
+		)
+		ORDER BY last_used_at DESC
+	`, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session := &models.Session{}
+		err := rows.Scan(&session.ID, &session.UserID, &session.Device, &session.IP, &session.CreatedAt, &session.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes the session with the given id, its access and refresh tokens stop working.
+func (r *PostgresRepository) RevokeSession(id, userID string) error {
+	res, err := r.db.Exec(
+		"UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// RevokeUserSessions revokes every session of the given user.
+func (r *PostgresRepository) RevokeUserSessions(userID string) error {
+	_, err := r.db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+// InsertPersonalAccessToken inserts a personal access token, only the hash of the token is stored.
+func (r *PostgresRepository) InsertPersonalAccessToken(token *models.PersonalAccessToken, tokenHash string) error {
+	_, err := r.db.Exec(
+		"INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		token.ID, token.UserID, token.Name, tokenHash, pq.Array(token.Scopes), token.ExpiresAt,
+	)
+
+	return err
+}
+
+// UsePersonalAccessToken returns the personal access token with the given hash if it is not revoked or expired
+// and updates the time it was last used. The expiration is sent by the client so it is stored in utc.
+func (r *PostgresRepository) UsePersonalAccessToken(tokenHash string) (*models.PersonalAccessToken, error) {
+	row := r.db.QueryRow(`
+		UPDATE personal_access_tokens SET last_used_at = NOW() FROM users
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW() AT TIME ZONE 'UTC')
+		AND users.id = personal_access_tokens.user_id
+		RETURNING personal_access_tokens.id, personal_access_tokens.user_id, users.username, personal_access_tokens.name,
+			personal_access_tokens.scopes, personal_access_tokens.created_at, personal_access_tokens.last_used_at,
+			personal_access_tokens.expires_at
+	`, tokenHash)
+
+	token := &models.PersonalAccessToken{}
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Username, &token.Name, pq.Array(&token.Scopes),
+		&token.CreatedAt, &token.LastUsedAt, &token.ExpiresAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// GetPersonalAccessTokens returns the personal access tokens of the given user that are not revoked.
+func (r *PostgresRepository) GetPersonalAccessTokens(userID string) ([]*models.PersonalAccessToken, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, scopes, created_at, last_used_at, expires_at FROM personal_access_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC
+	`, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	tokens := []*models.PersonalAccessToken{}
+	for rows.Next() {
+		token := &models.PersonalAccessToken{}
+		err := rows.Scan(
+			&token.ID, &token.UserID, &token.Name, pq.Array(&token.Scopes),
+			&token.CreatedAt, &token.LastUsedAt, &token.ExpiresAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// RevokePersonalAccessToken revokes the personal access token with the given id.
+func (r *PostgresRepository) RevokePersonalAccessToken(id, userID string) error {
+	res, err := r.db.Exec(
+		"UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// DeleteImage deletes an image with the given id of the given user.
+func (r *PostgresRepository) DeleteImage(id, userID string) error {
+	res, err := r.db.Exec("DELETE FROM images WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// DeleteUser deletes a user with the given id.
+func (r *PostgresRepository) DeleteUser(id string) error {
+	_, err := r.db.Exec("DELETE FROM users WHERE id = $1", id)
+	return err
+}
+
+// enqueueMail adds a mail to the outbox, it is due immediately.
+func enqueueMail(db execer, mail *models.OutboxMail) error {
+	_, err := db.Exec(
+		"INSERT INTO mail_outbox (id, type, receiver, username, token, locale) VALUES ($1, $2, $3, $4, $5, $6)",
+		mail.ID, mail.Type, mail.Receiver, mail.Username, mail.Token, mail.Locale,
+	)
+
 	return err
 }
 
-// DeleteFolder deletes a folder with the given id.
-func (r *PostgresRepository) DeleteFolder(id, userID string) error {
-	_, err := r.db.Exec("DELETE FROM folders WHERE id = $1 and user_id = $2", id, userID)
-	return err
+// EnqueueMail adds a mail to the outbox.
+func (r *PostgresRepository) EnqueueMail(mail *models.OutboxMail) error {
+	return enqueueMail(r.db, mail)
+}
+
+// ClaimMails claims the pending mails that are due and the mails of the workers that did not finish them
+// before their lease expired. The claimed mails are not due again until the lease expires, the rows locked
+// by other workers are skipped so every mail is claimed by one worker.
+func (r *PostgresRepository) ClaimMails(limit int, lease time.Duration) ([]*models.OutboxMail, error) {
+	rows, err := r.db.Query(
+		`UPDATE mail_outbox SET status = $1, attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM mail_outbox WHERE status IN ($3, $1) AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, receiver, username, token, locale, status, attempts, last_error`,
+		models.MailSending, lease.Seconds(), models.MailPending, limit,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	mails := []*models.OutboxMail{}
+	for rows.Next() {
+		mail := &models.OutboxMail{}
+		err := rows.Scan(
+			&mail.ID, &mail.Type, &mail.Receiver, &mail.Username, &mail.Token, &mail.Locale,
+			&mail.Status, &mail.Attempts, &mail.LastError,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mails = append(mails, mail)
+	}
+
+	return mails, rows.Err()
+}
+
+// MarkMailSent marks a claimed mail as sent and removes its token.
+func (r *PostgresRepository) MarkMailSent(id, messageID string) error {
+	res, err := r.db.Exec(
+		"UPDATE mail_outbox SET status = $1, message_id = $2, token = '', last_error = '', sent_at = NOW() WHERE id = $3 AND status = $4",
+		models.MailSent, messageID, id, models.MailSending,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// RetryMail schedules a new attempt of a claimed mail after the given delay.
+func (r *PostgresRepository) RetryMail(id, lastError string, delay time.Duration) error {
+	res, err := r.db.Exec(
+		"UPDATE mail_outbox SET status = $1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3) WHERE id = $4 AND status = $5",
+		models.MailPending, lastError, delay.Seconds(), id, models.MailSending,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// DeadLetterMail marks a claimed mail as dead, it is kept with its last error until it is requeued.
+func (r *PostgresRepository) DeadLetterMail(id, lastError string) error {
+	res, err := r.db.Exec(
+		"UPDATE mail_outbox SET status = $1, last_error = $2 WHERE id = $3 AND status = $4",
+		models.MailDead, lastError, id, models.MailSending,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// RequeueMails moves the dead mail with the given id, or every dead mail if the id is empty, back to the
+// outbox with its attempts reset and returns the number of requeued mails.
+func (r *PostgresRepository) RequeueMails(id string) (int64, error) {
+	res, err := r.db.Exec(
+		"UPDATE mail_outbox SET status = $1, attempts = 0, next_attempt_at = NOW() WHERE status = $2 AND ($3 = '' OR id = $3)",
+		models.MailPending, models.MailDead, id,
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// albumColumns are the columns selected for every album query, in the order expected by scanAlbum,
+// the images in the trash are not counted.
+const albumColumns = `id, name, user_id, cover_image_id, created_at, (
+		SELECT COUNT(*) FROM album_images JOIN images ON images.id = album_images.image_id
+		WHERE album_images.album_id = albums.id AND images.deleted_at IS NULL
+	)`
+
+// scanAlbum scans an album selected with albumColumns.
+func scanAlbum(row scanner) (*models.Album, error) {
+	album := &models.Album{}
+	err := row.Scan(&album.ID, &album.Name, &album.UserID, &album.CoverImageID, &album.CreatedAt, &album.ImageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return album, nil
+}
+
+// extraScanner scans the columns of a row followed by extra columns, so a row with more columns
+// than the ones of a scan function can be scanned by it.
+type extraScanner struct {
+	row   scanner
+	extra []interface{}
+}
+
+// Scan scans the row into dest and the extra destinations.
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+// qualifiedColumns returns the columns of a column list prefixed with the table name, for the queries with joins.
+func qualifiedColumns(table, columns string) string {
+	fields := strings.Split(columns, ",")
+	for i, field := range fields {
+		fields[i] = table + "." + strings.TrimSpace(field)
+	}
+
+	return strings.Join(fields, ", ")
+}
+
+// uniqueIDs returns the ids without the repeated ones, in the order of their first appearance.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// setAlbumCovers sets the cover of the albums, the chosen cover if it is not in the trash or the first image.
+func (r *PostgresRepository) setAlbumCovers(albums []*models.Album) error {
+	if len(albums) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(albums))
+	byID := make(map[string]*models.Album, len(albums))
+	for i, album := range albums {
+		ids[i] = album.ID
+		byID[album.ID] = album
+	}
+
+	rows, err := r.db.Query(`
+		SELECT DISTINCT ON (album_images.album_id) `+qualifiedColumns("images", imageColumns)+`, album_images.album_id
+		FROM album_images
+		JOIN albums ON albums.id = album_images.album_id
+		JOIN images ON images.id = album_images.image_id
+		WHERE album_images.album_id = ANY($1) AND images.deleted_at IS NULL
+		ORDER BY album_images.album_id, COALESCE(images.id = albums.cover_image_id, false) DESC,
+			album_images.position, images.id
+	`, pq.Array(ids))
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var albumID string
+		image, err := scanImage(extraScanner{row: rows, extra: []interface{}{&albumID}})
+		if err != nil {
+			return err
+		}
+
+		byID[albumID].Cover = image
+	}
+
+	return rows.Err()
 }
 
-// DeleteImage deletes an image with the given id.
-func (r *PostgresRepository) DeleteImage(id string) error {
-	_, err := r.db.Exec("DELETE FROM images WHERE id = $1", id)
+// InsertAlbum inserts an empty album into the database.
+func (r *PostgresRepository) InsertAlbum(album *models.Album) error {
+	_, err := r.db.Exec("INSERT INTO albums (id, name, user_id) VALUES ($1, $2, $3)", album.ID, album.Name, album.UserID)
 	return err
 }
 
-// DeleteUser deletes a user with the given id.
-func (r *PostgresRepository) DeleteUser(id string) error {
-	_, err := r.db.Exec("DELETE FROM users WHERE id = $1", id)
+// GetAlbum returns the album with the given id with its cover.
+func (r *PostgresRepository) GetAlbum(id string) (*models.Album, error) {
+	album, err := scanAlbum(r.db.QueryRow("SELECT "+albumColumns+" FROM albums WHERE id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.setAlbumCovers([]*models.Album{album}); err != nil {
+		return nil, err
+	}
+
+	return album, nil
+}
+
+// GetAlbums returns a page of the albums of the given user with their covers.
+func (r *PostgresRepository) GetAlbums(userID string, page *models.PageRequest) ([]*models.Album, string, error) {
+	query, args, limit, err := paginate("SELECT "+albumColumns+" FROM albums WHERE user_id = $1", []interface{}{userID}, page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer rows.Close()
+	albums := []*models.Album{}
+	for rows.Next() {
+		album, err := scanAlbum(rows)
+		if err != nil {
+			return nil, "", err
+		}
+
+		albums = append(albums, album)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(albums) > limit {
+		albums = albums[:limit]
+		last := albums[limit-1]
+		if next, err = encodeCursor(last.CreatedAt, last.ID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := r.setAlbumCovers(albums); err != nil {
+		return nil, "", err
+	}
+
+	return albums, next, nil
+}
+
+// UpdateAlbum changes the name and the cover of the album, ErrNotInAlbum is returned if the cover is not an image
+// of the album.
+func (r *PostgresRepository) UpdateAlbum(album *models.Album) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if album.CoverImageID != nil {
+		var inAlbum bool
+		row := tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM album_images JOIN images ON images.id = album_images.image_id
+				WHERE album_images.album_id = $1 AND album_images.image_id = $2 AND images.deleted_at IS NULL
+			)`,
+			album.ID, *album.CoverImageID,
+		)
+
+		if err := row.Scan(&inAlbum); err != nil {
+			return err
+		}
+
+		if !inAlbum {
+			return ErrNotInAlbum
+		}
+	}
+
+	res, err := tx.Exec(
+		"UPDATE albums SET name = $1, cover_image_id = $2 WHERE id = $3 AND user_id = $4",
+		album.Name, album.CoverImageID, album.ID, album.UserID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteAlbum deletes the album with the given id, its images are not deleted.
+func (r *PostgresRepository) DeleteAlbum(id, userID string) error {
+	res, err := r.db.Exec("DELETE FROM albums WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// lockAlbum locks the album with the given id until the end of the transaction and returns its owner,
+// so the positions of its images are changed by one transaction at a time.
+func lockAlbum(tx *sql.Tx, id string) (string, error) {
+	var userID string
+	if err := tx.QueryRow("SELECT user_id FROM albums WHERE id = $1 FOR UPDATE", id).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+
+		return "", err
+	}
+
+	return userID, nil
+}
+
+// AddAlbumImages adds the images to the end of the album in the given order and returns how many were added,
+// the images already in the album are skipped. ErrNotFound is returned if an image is not an image of the user who
+// adds it, the owner or a contributor of the album, or is in the trash.
+func (r *PostgresRepository) AddAlbumImages(albumID, userID string, imageIDs []string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+	if _, err := lockAlbum(tx, albumID); err != nil {
+		return 0, err
+	}
+
+	imageIDs = uniqueIDs(imageIDs)
+	var found int
+	row := tx.QueryRow(
+		"SELECT COUNT(*) FROM images WHERE id = ANY($1) AND user_id = $2 AND deleted_at IS NULL",
+		pq.Array(imageIDs), userID,
+	)
+
+	if err := row.Scan(&found); err != nil {
+		return 0, err
+	}
+
+	if found != len(imageIDs) {
+		return 0, ErrNotFound
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO album_images (album_id, image_id, position)
+		SELECT $1, ids.id, (SELECT COALESCE(MAX(position), 0) FROM album_images WHERE album_id = $1) + ids.n
+		FROM unnest($2::text[]) WITH ORDINALITY AS ids (id, n)
+		ON CONFLICT (album_id, image_id) DO NOTHING
+	`, albumID, pq.Array(imageIDs))
+
+	if err != nil {
+		return 0, err
+	}
+
+	added, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return added, tx.Commit()
+}
+
+// RemoveAlbumImage removes the image from the album, the album gets its first image as cover if the image was its cover.
+func (r *PostgresRepository) RemoveAlbumImage(albumID, imageID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	res, err := tx.Exec("DELETE FROM album_images WHERE album_id = $1 AND image_id = $2", albumID, imageID)
+	if err != nil {
+		return err
+	}
+
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE albums SET cover_image_id = NULL WHERE id = $1 AND cover_image_id = $2", albumID, imageID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderAlbumImages sets the order of the images of the album, the ids must be the images of the album that are
+// not in the trash, each of them once, or ErrInvalidAlbumOrder is returned.
+func (r *PostgresRepository) ReorderAlbumImages(albumID string, imageIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if _, err := lockAlbum(tx, albumID); err != nil {
+		return err
+	}
+
+	if len(uniqueIDs(imageIDs)) != len(imageIDs) {
+		return ErrInvalidAlbumOrder
+	}
+
+	var count int
+	row := tx.QueryRow(`
+		SELECT COUNT(*) FROM album_images JOIN images ON images.id = album_images.image_id
+		WHERE album_images.album_id = $1 AND images.deleted_at IS NULL
+	`, albumID)
+
+	if err := row.Scan(&count); err != nil {
+		return err
+	}
+
+	if count != len(imageIDs) {
+		return ErrInvalidAlbumOrder
+	}
+
+	res, err := tx.Exec(`
+		UPDATE album_images SET position = ids.n
+		FROM unnest($2::text[]) WITH ORDINALITY AS ids (id, n), images
+		WHERE album_images.album_id = $1 AND album_images.image_id = ids.id
+		AND images.id = ids.id AND images.deleted_at IS NULL
+	`, albumID, pq.Array(imageIDs))
+
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n != int64(count) {
+		if err != nil {
+			return err
+		}
+
+		return ErrInvalidAlbumOrder
+	}
+
+	return tx.Commit()
+}
+
+// GetAlbumImages returns a page of the images of the album sorted by their position, the images in the trash are
+// not returned. The pages have their own cursors because the images are not sorted by creation time.
+func (r *PostgresRepository) GetAlbumImages(albumID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	limit := pageSize(page)
+	op, order := pageOrder(page)
+	query := `
+		SELECT ` + qualifiedColumns("images", imageColumns) + `, album_images.position
+		FROM album_images JOIN images ON images.id = album_images.image_id
+		WHERE album_images.album_id = $1 AND images.deleted_at IS NULL`
+
+	args := []interface{}{albumID}
+	if page.Cursor != "" {
+		c, err := decodePositionCursor(page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+
+		args = append(args, c.Position, c.ID)
+		query += fmt.Sprintf(" AND (album_images.position, images.id) %s ($2, $3)", op)
+	}
+
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY album_images.position %s, images.id %s LIMIT $%d", order, order, len(args))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer rows.Close()
+	images := []*models.Image{}
+	positions := []int64{}
+	for rows.Next() {
+		var position int64
+		image, err := scanImage(extraScanner{row: rows, extra: []interface{}{&position}})
+		if err != nil {
+			return nil, "", err
+		}
+
+		images = append(images, image)
+		positions = append(positions, position)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(images) <= limit {
+		return images, "", nil
+	}
+
+	images = images[:limit]
+	next, err := encodePositionCursor(positions[limit-1], images[limit-1].ID)
+	return images, next, err
+}
+
+// SetAlbumCollaborator adds the user to the collaborators of the album with the given role, or changes
+// the role of a user who already collaborates in it.
+func (r *PostgresRepository) SetAlbumCollaborator(albumID, userID, role string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO album_collaborators (album_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (album_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		albumID, userID, role,
+	)
+
 	return err
 }
+
+// RemoveAlbumCollaborator removes the user from the collaborators of the album, ErrNotFound is returned
+// if the user does not collaborate in it.
+func (r *PostgresRepository) RemoveAlbumCollaborator(albumID, userID string) error {
+	res, err := r.db.Exec("DELETE FROM album_collaborators WHERE album_id = $1 AND user_id = $2", albumID, userID)
+	if err != nil {
+		return err
+	}
+
+	return affectedOne(res)
+}
+
+// GetAlbumRole returns the role of the user in the album, empty if the user does not collaborate in it.
+func (r *PostgresRepository) GetAlbumRole(userID, albumID string) (string, error) {
+	var role string
+	row := r.db.QueryRow("SELECT role FROM album_collaborators WHERE album_id = $1 AND user_id = $2", albumID, userID)
+	if err := row.Scan(&role); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return role, nil
+}
+
+// GetImageRole returns the highest role of the user in the albums that contain the image, empty if the
+// user does not collaborate in any of them. A contributor is higher than a viewer, and the owner of an album
+// has the contributor role in it so the owner can see the images added by the contributors.
+func (r *PostgresRepository) GetImageRole(userID, imageID string) (string, error) {
+	var role string
+	row := r.db.QueryRow(`
+		SELECT roles.role FROM (
+			SELECT album_collaborators.role FROM album_collaborators
+			JOIN album_images ON album_images.album_id = album_collaborators.album_id
+			WHERE album_collaborators.user_id = $1 AND album_images.image_id = $2
+			UNION ALL
+			SELECT 'contributor' FROM albums
+			JOIN album_images ON album_images.album_id = albums.id
+			WHERE albums.user_id = $1 AND album_images.image_id = $2
+		) AS roles
+		ORDER BY roles.role = 'contributor' DESC LIMIT 1`,
+		userID, imageID,
+	)
+
+	if err := row.Scan(&role); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return role, nil
+}
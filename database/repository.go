@@ -1,6 +1,29 @@
 package database
 
-import "github.com/DarioRoman01/photos/models"
+import (
+	"errors"
+	"time"
+
+	"github.com/DarioRoman01/photos/models"
+)
+
+var (
+	// ErrNotFound is returned when the row to change does not exist or does not belong to the user.
+	ErrNotFound = errors.New("not found")
+	// ErrTokenReused is returned when a refresh token that was already used is presented again,
+	// the session of the token is revoked because the token was probably stolen.
+	ErrTokenReused = errors.New("refresh token reused")
+	// ErrFolderExists is returned when a folder is created or moved to a path used by another folder of the user.
+	ErrFolderExists = errors.New("folder already exists")
+	// ErrFolderCycle is returned when a folder is moved into itself or into one of its descendants.
+	ErrFolderCycle = errors.New("folder can not be moved into itself")
+	// ErrRelocationInProgress is returned when a folder is relocated while another folder of the user is being relocated.
+	ErrRelocationInProgress = errors.New("folder relocation in progress")
+	// ErrNotInAlbum is returned when the cover of an album is not one of its images.
+	ErrNotInAlbum = errors.New("image not in album")
+	// ErrInvalidAlbumOrder is returned when the images of a new album order are not the images of the album.
+	ErrInvalidAlbumOrder = errors.New("invalid album order")
+)
 
 // DatabaseRepository is an interface that defines the methods that a database must implement.
 type DatabaseRepository interface {
@@ -12,32 +35,160 @@ type DatabaseRepository interface {
 	InsertUser(user *models.User) error
 	// UpdateUserStatus updates the status of a user to verified.
 	UpdateUserStatus(id string) error
+	// UpdateUserPassword replaces the password hash of a user if it is still the current one.
+	UpdateUserPassword(id, current, password string) error
 	// GetImage retrieves an image from the database.
 	GetImage(id string) (*models.Image, error)
+	// GetImageByURL retrieves the image stored at the given url from the database.
+	GetImageByURL(url string) (*models.Image, error)
 	// GetFolder retrieves a folder from the database.
 	GetFolder(id string) (*models.Folder, error)
-	// GetImages retrieves all images from the database  from the given user.
-	GetImages(userID, cursor string, limit int) ([]*models.Image, error, bool)
-	// GetImagesByFolder retrieves all images from the database  from the given folder.
-	GetImagesByFolder(userID, folderID, cursor string, limit int) ([]*models.Image, bool, error)
-	// GetFolders retrieves all folders from the database from the given user.
-	GetFolders(userID, cursor string, limit int) ([]*models.Folder, bool, error)
-	// CheckFolder checks if the folder exists if not exists its create a new folder with the given name
-	CheckFolder(userID, foldeName string) (string, error)
+	// GetImages retrieves a page of images from the database from the given user and the cursor of the next page.
+	GetImages(userID string, page *models.PageRequest) ([]*models.Image, string, error)
+	// GetImagesByFolder retrieves a page of images from the database from the given folder and the cursor of the next page.
+	GetImagesByFolder(userID, folderID string, page *models.PageRequest) ([]*models.Image, string, error)
+	// GetFolders retrieves a page of folders from the database from the given user and the cursor of the next page.
+	GetFolders(userID string, page *models.PageRequest) ([]*models.Folder, string, error)
+	// CheckFolder returns the id of the folder with the given path creating the missing folders along the way.
+	CheckFolder(userID, path string) (string, error)
+	// GetSubfolders retrieves a page of the folders in the given folder, or of all its descendants, and the cursor of the next page.
+	GetSubfolders(userID, folderID string, recursive bool, page *models.PageRequest) ([]*models.Folder, string, error)
+	// GetImagesByFolderTree retrieves a page of the images of the given folder and its descendants and the cursor of the next page.
+	GetImagesByFolderTree(userID, folderID string, page *models.PageRequest) ([]*models.Image, string, error)
+	// GetFolderBreadcrumbs retrieves the ancestors of a folder from the root followed by the folder.
+	GetFolderBreadcrumbs(id string) ([]*models.Folder, error)
+	// CreateFolderRelocation validates and journals the rename or move of a folder.
+	CreateFolderRelocation(relocation *models.FolderRelocation, lease time.Duration) error
+	// ClaimFolderRelocation takes over a journaled relocation whose lease expired.
+	ClaimFolderRelocation(lease time.Duration) (*models.FolderRelocation, error)
+	// RenewFolderRelocation extends the lease of a relocation.
+	RenewFolderRelocation(relocation *models.FolderRelocation, lease time.Duration) error
+	// CommitFolderRelocation moves the folder of a relocation and points its images to the new objects.
+	CommitFolderRelocation(relocation *models.FolderRelocation, objectURL func(key string) string) (*models.Folder, error)
+	// AbortFolderRelocation marks a relocation whose folder was not moved as aborted.
+	AbortFolderRelocation(relocation *models.FolderRelocation) error
+	// GetFolderTreeImages retrieves the images of a folder and its descendants with the paths of their folders.
+	GetFolderTreeImages(folderID string) ([]*models.TrashedImage, error)
+	// GetFolderRelocationKeys retrieves the old keys of the objects of the images committed by a relocation.
+	GetFolderRelocationKeys(relocationID string) ([]string, error)
+	// DeleteFolderRelocation removes a finished or rolled back relocation from the journal.
+	DeleteFolderRelocation(relocation *models.FolderRelocation) error
+	// CheckFolderRelocations returns ErrRelocationInProgress if a folder of the user is being relocated.
+	CheckFolderRelocations(userID string) error
 	// GetUserByUsername retrieves a user from the database by username.
 	GetUserByUsername(username string) (*models.User, error)
 	// GetUserByEmail retrieves a user from the database by email.
 	GetUserByEmail(email string) (*models.User, error)
 	// GetUserByID retrieves a user from the database by ID.
 	GetUserByID(id string) (*models.User, error)
-	// DeleteImage deletes an image from the database.
-	DeleteImage(id string) error
-	// DeleteFolder deletes a folder from the database.
-	DeleteFolder(id, userID string) error
+	// DeleteImage deletes an image of the given user from the database.
+	DeleteImage(id, userID string) error
+	// DeleteFolderTree deletes a folder, its descendants and their images from the database.
+	DeleteFolderTree(id, userID string) (*models.DeletedFolder, error)
+	// InsertAlbum inserts an empty album into the database.
+	InsertAlbum(album *models.Album) error
+	// GetAlbum retrieves an album with its cover from the database.
+	GetAlbum(id string) (*models.Album, error)
+	// GetAlbums retrieves a page of the albums of a user with their covers.
+	GetAlbums(userID string, page *models.PageRequest) ([]*models.Album, string, error)
+	// UpdateAlbum changes the name and the cover of an album.
+	UpdateAlbum(album *models.Album) error
+	// DeleteAlbum deletes an album without deleting its images.
+	DeleteAlbum(id, userID string) error
+	// AddAlbumImages adds images to the end of an album and returns how many were added.
+	AddAlbumImages(albumID, userID string, imageIDs []string) (int64, error)
+	// RemoveAlbumImage removes an image from an album.
+	RemoveAlbumImage(albumID, imageID string) error
+	// ReorderAlbumImages sets the order of the images of an album.
+	ReorderAlbumImages(albumID string, imageIDs []string) error
+	// GetAlbumImages retrieves a page of the images of an album sorted by their position.
+	GetAlbumImages(albumID string, page *models.PageRequest) ([]*models.Image, string, error)
+	// SetAlbumCollaborator adds a collaborator to an album or changes its role.
+	SetAlbumCollaborator(albumID, userID, role string) error
+	// RemoveAlbumCollaborator removes a collaborator from an album.
+	RemoveAlbumCollaborator(albumID, userID string) error
+	// GetAlbumRole retrieves the role of a user in an album, empty if the user does not collaborate in it.
+	GetAlbumRole(userID, albumID string) (string, error)
+	// GetImageRole retrieves the highest role of a user in the albums that contain an image, empty if there is none.
+	GetImageRole(userID, imageID string) (string, error)
 	// DeleteUser deletes a user from the database.
 	DeleteUser(id string) error
-	// UpdateImage updates the image with the given id only the folder and the url can be chage.
-	UpdateImage(req *models.MoveFileRequest, userId string) error
+	// UpdateImage moves the image with the given id from the folder with the old path, only the folder and the urls can be chage.
+	UpdateImage(req *models.MoveFileRequest, oldPath, userId string) error
+	// TrashImage moves an image to the trash.
+	TrashImage(id, userID string) error
+	// TrashFolder moves a folder and its images to the trash.
+	TrashFolder(id, userID string) error
+	// RestoreImage moves an image out of the trash.
+	RestoreImage(id, userID string) error
+	// RestoreFolder moves a folder and the images trashed with it out of the trash.
+	RestoreFolder(id, userID string) error
+	// GetTrashedImages retrieves a page of the images in the trash of the given user and the cursor of the next page.
+	GetTrashedImages(userID string, page *models.PageRequest) ([]*models.Image, string, error)
+	// GetTrashedFolders retrieves a page of the folders in the trash of the given user and the cursor of the next page.
+	GetTrashedFolders(userID string, page *models.PageRequest) ([]*models.Folder, string, error)
+	// GetPurgeableImages retrieves the images that are in the trash for at least the given time.
+	GetPurgeableImages(userID string, age time.Duration, limit int) ([]*models.TrashedImage, error)
+	// PurgeFolders deletes the empty folders that are in the trash for at least the given time.
+	PurgeFolders(userID string, age time.Duration) error
+	// InsertShareLink inserts a share link into the database.
+	InsertShareLink(link *models.ShareLink) error
+	// GetShareLinkByToken retrieves a share link from the database by token.
+	GetShareLinkByToken(token string) (*models.ShareLink, error)
+	// GetShareLinks retrieves a page of the share links of the given user and the cursor of the next page.
+	GetShareLinks(userID string, page *models.PageRequest) ([]*models.ShareLink, string, error)
+	// IncrementShareLinkViews adds a view to a share link.
+	IncrementShareLinkViews(id string) error
+	// RevokeShareLink revokes a share link of the given user.
+	RevokeShareLink(id, userID string) error
+	// InsertSession inserts a session and its first refresh token into the database.
+	InsertSession(session *models.Session, refreshTokenHash string, ttl time.Duration) error
+	// RotateRefreshToken replaces a refresh token with a new one and returns its session.
+	RotateRefreshToken(oldHash, newHash string, ttl time.Duration) (*models.Session, error)
+	// IsSessionActive checks if a session exists and is not revoked.
+	IsSessionActive(id string) (bool, error)
+	// GetSessions retrieves the active sessions of the given user.
+	GetSessions(userID string) ([]*models.Session, error)
+	// RevokeSession revokes a session of the given user.
+	RevokeSession(id, userID string) error
+	// RevokeUserSessions revokes every session of the given user.
+	RevokeUserSessions(userID string) error
+	// InsertPersonalAccessToken inserts a personal access token into the database.
+	InsertPersonalAccessToken(token *models.PersonalAccessToken, tokenHash string) error
+	// UsePersonalAccessToken retrieves the active personal access token with the given hash and records its use.
+	UsePersonalAccessToken(tokenHash string) (*models.PersonalAccessToken, error)
+	// GetPersonalAccessTokens retrieves the active personal access tokens of the given user.
+	GetPersonalAccessTokens(userID string) ([]*models.PersonalAccessToken, error)
+	// RevokePersonalAccessToken revokes a personal access token of the given user.
+	RevokePersonalAccessToken(id, userID string) error
+	// SetTOTPSecret stores a pending TOTP secret of a user.
+	SetTOTPSecret(userID, secret string) error
+	// EnableTOTP enables the TOTP of a user and replaces its recovery codes.
+	EnableTOTP(userID string, recoveryCodeHashes []string) error
+	// DisableTOTP disables the TOTP of a user and deletes its recovery codes.
+	DisableTOTP(userID string) error
+	// UseTOTPStep records the time step of a TOTP code of a user, a step can only be used once.
+	UseTOTPStep(userID string, step int64) error
+	// UseRecoveryCode marks a recovery code of a user as used.
+	UseRecoveryCode(userID, codeHash string) error
+	// GetUserByIdentity retrieves the user linked to an identity of an external identity provider.
+	GetUserByIdentity(issuer, subject string) (*models.User, error)
+	// InsertIdentity links an identity of an external identity provider to a user.
+	InsertIdentity(identity *models.Identity) error
+	// InsertUserWithMail inserts a user and adds a mail to the outbox in the same transaction.
+	InsertUserWithMail(user *models.User, mail *models.OutboxMail) error
+	// EnqueueMail adds a mail to the outbox.
+	EnqueueMail(mail *models.OutboxMail) error
+	// ClaimMails claims the mails of the outbox that are due for the given time and returns them.
+	ClaimMails(limit int, lease time.Duration) ([]*models.OutboxMail, error)
+	// MarkMailSent marks a mail of the outbox as sent.
+	MarkMailSent(id, messageID string) error
+	// RetryMail schedules a new attempt of a mail of the outbox that failed.
+	RetryMail(id, lastError string, delay time.Duration) error
+	// DeadLetterMail stops the attempts of a mail of the outbox that failed.
+	DeadLetterMail(id, lastError string) error
+	// RequeueMails moves a dead mail, or every dead mail if the id is empty, back to the outbox and returns how many.
+	RequeueMails(id string) (int64, error)
 }
 
 var databaseRepository DatabaseRepository
@@ -62,32 +213,88 @@ func GetImage(id string) (*models.Image, error) {
 	return databaseRepository.GetImage(id)
 }
 
+func GetImageByURL(url string) (*models.Image, error) {
+	return databaseRepository.GetImageByURL(url)
+}
+
 func GetFolder(id string) (*models.Folder, error) {
 	return databaseRepository.GetFolder(id)
 }
 
-func GetImages(userID, cursor string, limit int) ([]*models.Image, error, bool) {
-	return databaseRepository.GetImages(userID, cursor, limit)
+func GetImages(userID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return databaseRepository.GetImages(userID, page)
 }
 
-func GetFolders(userID, cursor string, limit int) ([]*models.Folder, bool, error) {
-	return databaseRepository.GetFolders(userID, cursor, limit)
+func GetFolders(userID string, page *models.PageRequest) ([]*models.Folder, string, error) {
+	return databaseRepository.GetFolders(userID, page)
 }
 
-func GetImagesByFolder(userID, folderID, cursor string, limit int) ([]*models.Image, bool, error) {
-	return databaseRepository.GetImagesByFolder(userID, folderID, cursor, limit)
+func GetImagesByFolder(userID, folderID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return databaseRepository.GetImagesByFolder(userID, folderID, page)
 }
 
 func UpdateUserStatus(id string) error {
 	return databaseRepository.UpdateUserStatus(id)
 }
 
-func UpdateImage(req *models.MoveFileRequest, userId string) error {
-	return databaseRepository.UpdateImage(req, userId)
+func UpdateUserPassword(id, current, password string) error {
+	return databaseRepository.UpdateUserPassword(id, current, password)
 }
 
-func CheckFolder(userID, foldeName string) (string, error) {
-	return databaseRepository.CheckFolder(userID, foldeName)
+func UpdateImage(req *models.MoveFileRequest, oldPath, userId string) error {
+	return databaseRepository.UpdateImage(req, oldPath, userId)
+}
+
+func CheckFolder(userID, path string) (string, error) {
+	return databaseRepository.CheckFolder(userID, path)
+}
+
+func GetSubfolders(userID, folderID string, recursive bool, page *models.PageRequest) ([]*models.Folder, string, error) {
+	return databaseRepository.GetSubfolders(userID, folderID, recursive, page)
+}
+
+func GetImagesByFolderTree(userID, folderID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return databaseRepository.GetImagesByFolderTree(userID, folderID, page)
+}
+
+func GetFolderBreadcrumbs(id string) ([]*models.Folder, error) {
+	return databaseRepository.GetFolderBreadcrumbs(id)
+}
+
+func CreateFolderRelocation(relocation *models.FolderRelocation, lease time.Duration) error {
+	return databaseRepository.CreateFolderRelocation(relocation, lease)
+}
+
+func ClaimFolderRelocation(lease time.Duration) (*models.FolderRelocation, error) {
+	return databaseRepository.ClaimFolderRelocation(lease)
+}
+
+func RenewFolderRelocation(relocation *models.FolderRelocation, lease time.Duration) error {
+	return databaseRepository.RenewFolderRelocation(relocation, lease)
+}
+
+func CommitFolderRelocation(relocation *models.FolderRelocation, objectURL func(key string) string) (*models.Folder, error) {
+	return databaseRepository.CommitFolderRelocation(relocation, objectURL)
+}
+
+func AbortFolderRelocation(relocation *models.FolderRelocation) error {
+	return databaseRepository.AbortFolderRelocation(relocation)
+}
+
+func GetFolderTreeImages(folderID string) ([]*models.TrashedImage, error) {
+	return databaseRepository.GetFolderTreeImages(folderID)
+}
+
+func GetFolderRelocationKeys(relocationID string) ([]string, error) {
+	return databaseRepository.GetFolderRelocationKeys(relocationID)
+}
+
+func DeleteFolderRelocation(relocation *models.FolderRelocation) error {
+	return databaseRepository.DeleteFolderRelocation(relocation)
+}
+
+func CheckFolderRelocations(userID string) error {
+	return databaseRepository.CheckFolderRelocations(userID)
 }
 
 func GetUserByUsername(username string) (*models.User, error) {
@@ -102,14 +309,214 @@ func GetUserByID(id string) (*models.User, error) {
 	return databaseRepository.GetUserByID(id)
 }
 
-func DeleteImage(id string) error {
-	return databaseRepository.DeleteImage(id)
+func DeleteImage(id, userID string) error {
+	return databaseRepository.DeleteImage(id, userID)
+}
+
+func DeleteFolderTree(id, userID string) (*models.DeletedFolder, error) {
+	return databaseRepository.DeleteFolderTree(id, userID)
 }
 
-func DeleteFolder(id, userID string) error {
-	return databaseRepository.DeleteFolder(id, userID)
+func InsertAlbum(album *models.Album) error {
+	return databaseRepository.InsertAlbum(album)
+}
+
+func GetAlbum(id string) (*models.Album, error) {
+	return databaseRepository.GetAlbum(id)
+}
+
+func GetAlbums(userID string, page *models.PageRequest) ([]*models.Album, string, error) {
+	return databaseRepository.GetAlbums(userID, page)
+}
+
+func UpdateAlbum(album *models.Album) error {
+	return databaseRepository.UpdateAlbum(album)
+}
+
+func DeleteAlbum(id, userID string) error {
+	return databaseRepository.DeleteAlbum(id, userID)
+}
+
+func AddAlbumImages(albumID, userID string, imageIDs []string) (int64, error) {
+	return databaseRepository.AddAlbumImages(albumID, userID, imageIDs)
+}
+
+func RemoveAlbumImage(albumID, imageID string) error {
+	return databaseRepository.RemoveAlbumImage(albumID, imageID)
+}
+
+func ReorderAlbumImages(albumID string, imageIDs []string) error {
+	return databaseRepository.ReorderAlbumImages(albumID, imageIDs)
+}
+
+func GetAlbumImages(albumID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return databaseRepository.GetAlbumImages(albumID, page)
+}
+
+func SetAlbumCollaborator(albumID, userID, role string) error {
+	return databaseRepository.SetAlbumCollaborator(albumID, userID, role)
+}
+
+func RemoveAlbumCollaborator(albumID, userID string) error {
+	return databaseRepository.RemoveAlbumCollaborator(albumID, userID)
+}
+
+func GetAlbumRole(userID, albumID string) (string, error) {
+	return databaseRepository.GetAlbumRole(userID, albumID)
+}
+
+func GetImageRole(userID, imageID string) (string, error) {
+	return databaseRepository.GetImageRole(userID, imageID)
 }
 
 func DeleteUser(id string) error {
 	return databaseRepository.DeleteUser(id)
 }
+
+func TrashImage(id, userID string) error {
+	return databaseRepository.TrashImage(id, userID)
+}
+
+func TrashFolder(id, userID string) error {
+	return databaseRepository.TrashFolder(id, userID)
+}
+
+func RestoreImage(id, userID string) error {
+	return databaseRepository.RestoreImage(id, userID)
+}
+
+func RestoreFolder(id, userID string) error {
+	return databaseRepository.RestoreFolder(id, userID)
+}
+
+func GetTrashedImages(userID string, page *models.PageRequest) ([]*models.Image, string, error) {
+	return databaseRepository.GetTrashedImages(userID, page)
+}
+
+func GetTrashedFolders(userID string, page *models.PageRequest) ([]*models.Folder, string, error) {
+	return databaseRepository.GetTrashedFolders(userID, page)
+}
+
+func GetPurgeableImages(userID string, age time.Duration, limit int) ([]*models.TrashedImage, error) {
+	return databaseRepository.GetPurgeableImages(userID, age, limit)
+}
+
+func PurgeFolders(userID string, age time.Duration) error {
+	return databaseRepository.PurgeFolders(userID, age)
+}
+
+func InsertShareLink(link *models.ShareLink) error {
+	return databaseRepository.InsertShareLink(link)
+}
+
+func GetShareLinkByToken(token string) (*models.ShareLink, error) {
+	return databaseRepository.GetShareLinkByToken(token)
+}
+
+func GetShareLinks(userID string, page *models.PageRequest) ([]*models.ShareLink, string, error) {
+	return databaseRepository.GetShareLinks(userID, page)
+}
+
+func IncrementShareLinkViews(id string) error {
+	return databaseRepository.IncrementShareLinkViews(id)
+}
+
+func RevokeShareLink(id, userID string) error {
+	return databaseRepository.RevokeShareLink(id, userID)
+}
+
+func InsertSession(session *models.Session, refreshTokenHash string, ttl time.Duration) error {
+	return databaseRepository.InsertSession(session, refreshTokenHash, ttl)
+}
+
+func RotateRefreshToken(oldHash, newHash string, ttl time.Duration) (*models.Session, error) {
+	return databaseRepository.RotateRefreshToken(oldHash, newHash, ttl)
+}
+
+func IsSessionActive(id string) (bool, error) {
+	return databaseRepository.IsSessionActive(id)
+}
+
+func GetSessions(userID string) ([]*models.Session, error) {
+	return databaseRepository.GetSessions(userID)
+}
+
+func RevokeSession(id, userID string) error {
+	return databaseRepository.RevokeSession(id, userID)
+}
+
+func RevokeUserSessions(userID string) error {
+	return databaseRepository.RevokeUserSessions(userID)
+}
+
+func InsertPersonalAccessToken(token *models.PersonalAccessToken, tokenHash string) error {
+	return databaseRepository.InsertPersonalAccessToken(token, tokenHash)
+}
+
+func UsePersonalAccessToken(tokenHash string) (*models.PersonalAccessToken, error) {
+	return databaseRepository.UsePersonalAccessToken(tokenHash)
+}
+
+func GetPersonalAccessTokens(userID string) ([]*models.PersonalAccessToken, error) {
+	return databaseRepository.GetPersonalAccessTokens(userID)
+}
+
+func RevokePersonalAccessToken(id, userID string) error {
+	return databaseRepository.RevokePersonalAccessToken(id, userID)
+}
+
+func SetTOTPSecret(userID, secret string) error {
+	return databaseRepository.SetTOTPSecret(userID, secret)
+}
+
+func EnableTOTP(userID string, recoveryCodeHashes []string) error {
+	return databaseRepository.EnableTOTP(userID, recoveryCodeHashes)
+}
+
+func DisableTOTP(userID string) error {
+	return databaseRepository.DisableTOTP(userID)
+}
+
+func UseTOTPStep(userID string, step int64) error {
+	return databaseRepository.UseTOTPStep(userID, step)
+}
+
+func UseRecoveryCode(userID, codeHash string) error {
+	return databaseRepository.UseRecoveryCode(userID, codeHash)
+}
+
+func GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	return databaseRepository.GetUserByIdentity(issuer, subject)
+}
+
+func InsertIdentity(identity *models.Identity) error {
+	return databaseRepository.InsertIdentity(identity)
+}
+
+func InsertUserWithMail(user *models.User, mail *models.OutboxMail) error {
+	return databaseRepository.InsertUserWithMail(user, mail)
+}
+
+func EnqueueMail(mail *models.OutboxMail) error {
+	return databaseRepository.EnqueueMail(mail)
+}
+
+func ClaimMails(limit int, lease time.Duration) ([]*models.OutboxMail, error) {
+	return databaseRepository.ClaimMails(limit, lease)
+}
+
+func MarkMailSent(id, messageID string) error {
+	return databaseRepository.MarkMailSent(id, messageID)
+}
+
+func RetryMail(id, lastError string, delay time.Duration) error {
+	return databaseRepository.RetryMail(id, lastError, delay)
+}
+
+func DeadLetterMail(id, lastError string) error {
+	return databaseRepository.DeadLetterMail(id, lastError)
+}
+
+func RequeueMails(id string) (int64, error) {
+	return databaseRepository.RequeueMails(id)
+}
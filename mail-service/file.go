@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes the mails as .eml files in a directory, or to the standard output if the
+// directory is empty, so they can be read without a mail provider in development and tests.
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer returns a new FileMailer that writes the mails to the given directory.
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	id, err := messageID(msg.From)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := msg.Bytes(id)
+	if err != nil {
+		return "", err
+	}
+
+	if m.dir == "" {
+		_, err := fmt.Fprintf(os.Stdout, "%s\n", data)
+		return id, err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// the files are named by time so they are listed in the order they were sent
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), strings.Trim(id, "<>"))
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0o644); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a mail ready to be sent by a Mailer.
+type Message struct {
+	From    string // From is the address of the sender.
+	To      string // To is the address of the receiver.
+	Subject string // Subject is the subject of the mail.
+	Text    string // Text is the plain text body of the mail.
+	HTML    string // HTML is the html body of the mail, the mail is only plain text if it is empty.
+}
+
+// Mailer sends the mails.
+type Mailer interface {
+	// Send sends the message and returns its id.
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// NewMailer returns the mailer selected by the MAIL_BACKEND env variable, mailgun by default,
+// smtp to send the mails to an SMTP server or file to write them as .eml files.
+func NewMailer() (Mailer, error) {
+	switch backend := os.Getenv("MAIL_BACKEND"); backend {
+	case "", "mailgun":
+		return NewMailgunMailer(os.Getenv("MAILGUN_DOMAIN"), os.Getenv("MAILGUN_API_KEY")), nil
+	case "smtp":
+		return NewSMTPMailerFromEnv()
+	case "file":
+		return NewFileMailer(os.Getenv("MAIL_DIR")), nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", backend)
+	}
+}
+
+// mailFrom returns the sender address of the mails.
+func mailFrom() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+
+	if domain := os.Getenv("MAILGUN_DOMAIN"); domain != "" {
+		return fmt.Sprintf("mailgun@%s", domain)
+	}
+
+	return "photos@localhost"
+}
+
+// messageID returns a new Message-ID header value for the domain of the sender.
+func messageID(from string) (string, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", err
+	}
+
+	domain := "localhost"
+	if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+		domain = d
+	}
+
+	return fmt.Sprintf("<%s@%s>", uuid.NewString(), domain), nil
+}
+
+// Bytes formats the message as an RFC 5322 mail with the given Message-ID.
+func (m *Message) Bytes(id string) ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receiver: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: %s\r\n", id)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if m.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, m.Text); err != nil {
+			return nil, err
+		}
+
+		buf.WriteString("\r\n")
+		return buf.Bytes(), nil
+	}
+
+	// the parts go from the simplest to the richest, the clients show the last one they support
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	bodies := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+
+	for _, b := range bodies {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {b.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeQuotedPrintable(part, b.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes the body with the quoted-printable encoding and crlf line endings.
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
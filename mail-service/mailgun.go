@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+
+	"github.com/mailgun/mailgun-go/v3"
+)
+
+// MailgunMailer sends the mails with the Mailgun api.
+type MailgunMailer struct {
+	mg *mailgun.MailgunImpl
+}
+
+// NewMailgunMailer returns a new MailgunMailer, the client is created once and reused by every mail.
+func NewMailgunMailer(domain, apiKey string) *MailgunMailer {
+	return &MailgunMailer{mg: mailgun.NewMailgun(domain, apiKey)}
+}
+
+func (m *MailgunMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	message := m.mg.NewMessage(msg.From, msg.Subject, msg.Text, msg.To)
+	if msg.HTML != "" {
+		message.SetHtml(msg.HTML)
+	}
+
+	_, id, err := m.mg.Send(ctx, message)
+	return id, err
+}
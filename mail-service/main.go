@@ -3,7 +3,9 @@ package main
 import (
 	"log"
 	"net"
+	"os"
 
+	"github.com/DarioRoman01/photos/database"
 	"github.com/DarioRoman01/photos/mailpb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -15,7 +17,29 @@ func main() {
 		log.Fatalf("Error listening: %s", err.Error())
 	}
 
-	server := NewServer()
+	mailer, err := NewMailer()
+	if err != nil {
+		log.Fatalf("Error creating mailer: %s", err.Error())
+	}
+
+	templates, err := LoadTemplates()
+	if err != nil {
+		log.Fatalf("Error loading templates: %s", err.Error())
+	}
+
+	db, err := database.NewPostgresRepository(os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		log.Fatalf("Error connecting to the database: %s", err.Error())
+	}
+
+	interval, err := outboxInterval()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	database.SetDatabaseRepository(db)
+	server := NewServer(mailer, templates)
+	go server.sendOutbox(interval)
 	grpcServer := grpc.NewServer()
 	mailpb.RegisterMailServiceServer(grpcServer, server)
 	reflection.Register(grpcServer)
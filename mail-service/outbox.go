@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/mailpb"
+	"github.com/DarioRoman01/photos/models"
+)
+
+const (
+	// outboxBatchSize is the number of mails claimed by every poll of the outbox, the mails of a batch
+	// are sent one after the other so the batch must be sent within the lease.
+	outboxBatchSize = 4
+	// outboxLease is the time a worker has to send the claimed mails before other worker can claim them.
+	outboxLease = 5 * time.Minute
+	// sendTimeout is the maximum time of the delivery of a mail, outboxBatchSize times sendTimeout is
+	// less than outboxLease so a slow batch is sent before the lease ends.
+	sendTimeout = time.Minute
+	// retryBaseDelay is the wait after the first failed attempt of a mail, it doubles with every attempt.
+	retryBaseDelay = 30 * time.Second
+	// retryMaxDelay is the maximum wait between two attempts of a mail.
+	retryMaxDelay = time.Hour
+	// maxAttempts is the number of failed attempts after which a mail is moved to the dead letters.
+	maxAttempts = 10
+)
+
+// retryDelay returns the wait before the next attempt of a mail that failed the given number of times.
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+
+	return delay
+}
+
+// outboxInterval returns the time between the polls of the outbox, read from MAIL_OUTBOX_INTERVAL.
+func outboxInterval() (time.Duration, error) {
+	interval := os.Getenv("MAIL_OUTBOX_INTERVAL")
+	if interval == "" {
+		return 5 * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid MAIL_OUTBOX_INTERVAL: %s", interval)
+	}
+
+	return d, nil
+}
+
+// deliver sends a claimed mail and records the result, the mails with an unknown template are moved
+// to the dead letters immediately because they can never be sent.
+func (s *Server) deliver(ctx context.Context, mail *models.OutboxMail) error {
+	msg, err := s.CreateMessage(&mailpb.SendMailRequest{
+		Type:     mail.Type,
+		Receiver: mail.Receiver,
+		User:     mail.Username,
+		Token:    mail.Token,
+		Locale:   mail.Locale,
+	})
+
+	if err == nil {
+		var id string
+		if id, err = s.mailer.Send(ctx, msg); err == nil {
+			return database.MarkMailSent(mail.ID, id)
+		}
+	}
+
+	if errors.Is(err, errUnknownTemplate) || mail.Attempts >= maxAttempts {
+		log.Printf("Mail %s moved to the dead letters after %d attempts: %v", mail.ID, mail.Attempts, err)
+		return database.DeadLetterMail(mail.ID, err.Error())
+	}
+
+	return database.RetryMail(mail.ID, err.Error(), retryDelay(mail.Attempts))
+}
+
+// processOutbox sends the due mails of the outbox until there are none.
+func (s *Server) processOutbox() error {
+	for {
+		// the batch stops at the end of the lease even if recording the deliveries is slow, the mails
+		// left are claimed again by the next poll after the lease
+		lease, cancelLease := context.WithTimeout(context.Background(), outboxLease)
+		mails, err := database.ClaimMails(outboxBatchSize, outboxLease)
+		if err != nil {
+			cancelLease()
+			return err
+		}
+
+		for _, mail := range mails {
+			if lease.Err() != nil {
+				break
+			}
+
+			ctx, cancel := context.WithTimeout(lease, sendTimeout)
+			err := s.deliver(ctx, mail)
+			cancel()
+			if err != nil {
+				log.Printf("Error recording the delivery of mail %s: %v", mail.ID, err)
+			}
+		}
+
+		cancelLease()
+
+		if len(mails) < outboxBatchSize {
+			return nil
+		}
+	}
+}
+
+// sendOutbox periodically sends the mails of the outbox.
+func (s *Server) sendOutbox(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := s.processOutbox(); err != nil {
+			log.Printf("Error processing outbox: %v", err)
+		}
+	}
+}
+
+// RequeueMail moves a dead mail, or every dead mail if the id is empty, back to the outbox. It is an
+// admin rpc, the mail service must only be reachable by the other services and the operators.
+func (s *Server) RequeueMail(ctx context.Context, in *mailpb.RequeueMailRequest) (*mailpb.RequeueMailResponse, error) {
+	requeued, err := database.RequeueMails(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &mailpb.RequeueMailResponse{Requeued: requeued}, nil
+}
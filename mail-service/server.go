@@ -2,46 +2,84 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"os"
 
 	"github.com/DarioRoman01/photos/mailpb"
-	"github.com/mailgun/mailgun-go/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
 	mailpb.UnimplementedMailServiceServer
+	mailer    Mailer     // mailer sends the mails.
+	templates *Templates // templates render the mails.
 }
 
-func NewServer() *Server {
-	return &Server{}
+func NewServer(mailer Mailer, templates *Templates) *Server {
+	return &Server{mailer: mailer, templates: templates}
 }
 
-func (s *Server) CreateMessage(r *mailpb.SendMailRequest, mg *mailgun.MailgunImpl) (*mailgun.Message, error) {
-	msg := mg.NewMessage(
-		fmt.Sprintf("mailgun@%s", os.Getenv("MAILGUN_DOMAIN")),
-		r.GetSubject(),
-		r.GetBody(),
-		r.GetReceiver(),
-	)
-
-	msg.SetTemplate(r.GetType())
-	msg.AddTemplateVariable("token", r.GetToken())
-	msg.AddTemplateVariable("username", r.GetUser())
-	return msg, nil
+// renderError returns the grpc error of a failed render.
+func renderError(err error) error {
+	if errors.Is(err, errUnknownTemplate) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
 }
 
-func (s *Server) SendMail(ctx context.Context, in *mailpb.SendMailRequest) (*mailpb.SendMailResponse, error) {
-	mg := mailgun.NewMailgun(os.Getenv("MAILGUN_DOMAIN"), os.Getenv("MAILGUN_API_KEY"))
-	msg, err := s.CreateMessage(in, mg)
+// CreateMessage renders the templates of the type of the request in its locale.
+func (s *Server) CreateMessage(r *mailpb.SendMailRequest) (*Message, error) {
+	mail, err := s.templates.Render(r.GetType(), r.GetLocale(), TemplateData{
+		Username: r.GetUser(),
+		Token:    r.GetToken(),
+		AppURL:   os.Getenv("APP_URL"),
+	})
+
 	if err != nil {
 		return nil, err
 	}
 
-	_, id, err := mg.Send(ctx, msg)
+	return &Message{
+		From:    mailFrom(),
+		To:      r.GetReceiver(),
+		Subject: mail.Subject,
+		Text:    mail.Text,
+		HTML:    mail.HTML,
+	}, nil
+}
+
+func (s *Server) SendMail(ctx context.Context, in *mailpb.SendMailRequest) (*mailpb.SendMailResponse, error) {
+	msg, err := s.CreateMessage(in)
+	if err != nil {
+		return nil, renderError(err)
+	}
+
+	id, err := s.mailer.Send(ctx, msg)
 	if err != nil {
 		return nil, err
 	}
 
 	return &mailpb.SendMailResponse{Id: id}, nil
 }
+
+// PreviewMail renders the templates of a type with sample data without sending the mail.
+func (s *Server) PreviewMail(ctx context.Context, in *mailpb.PreviewMailRequest) (*mailpb.PreviewMailResponse, error) {
+	mail, err := s.templates.Render(in.GetType(), in.GetLocale(), TemplateData{
+		Username: "jane",
+		Token:    "sample-token",
+		AppURL:   os.Getenv("APP_URL"),
+	})
+
+	if err != nil {
+		return nil, renderError(err)
+	}
+
+	return &mailpb.PreviewMailResponse{
+		Subject: mail.Subject,
+		Text:    mail.Text,
+		Html:    mail.HTML,
+		Locale:  mail.Locale,
+	}, nil
+}
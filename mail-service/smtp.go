@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+)
+
+// SMTPMailer sends the mails to an SMTP server, the connection is upgraded with STARTTLS.
+type SMTPMailer struct {
+	host     string // host is the hostname of the server.
+	port     int    // port is the port of the server, usually 587.
+	username string // username is the user of the server, empty if the server does not require authentication.
+	password string // password is the password of the user.
+	startTLS bool   // startTLS requires the server to support STARTTLS, it can only be disabled for local servers.
+}
+
+// NewSMTPMailerFromEnv returns a new SMTPMailer configured with the SMTP_* env variables.
+func NewSMTPMailerFromEnv() (*SMTPMailer, error) {
+	m := &SMTPMailer{
+		host:     os.Getenv("SMTP_HOST"),
+		port:     587,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		startTLS: os.Getenv("SMTP_STARTTLS") != "false",
+	}
+
+	if m.host == "" {
+		return nil, errors.New("SMTP_HOST is required")
+	}
+
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+
+		m.port = p
+	}
+
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	id, err := messageID(msg.From)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := msg.Bytes(id)
+	if err != nil {
+		return "", err
+	}
+
+	from, _ := mail.ParseAddress(msg.From)
+	to, _ := mail.ParseAddress(msg.To)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.host, strconv.Itoa(m.port)))
+	if err != nil {
+		return "", err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return "", err
+		}
+	} else if m.startTLS {
+		return "", errors.New("the smtp server does not support STARTTLS")
+	}
+
+	if m.username != "" {
+		// net/smtp refuses to send the password without tls unless the server is local
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return "", err
+	}
+
+	if err := client.Rcpt(to.Address); err != nil {
+		return "", err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return id, client.Quit()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// defaultLocale is the locale of the mails when the locale of the request has no templates.
+const defaultLocale = "en"
+
+// templateFiles are the mail templates, templates/<locale>/<type>.txt is the plain text body and defines the
+// subject, templates/<locale>/<type>.html is the content of the html body rendered in templates/layout.html.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// errUnknownTemplate is returned when there are no templates for the type of a mail.
+var errUnknownTemplate = errors.New("unknown mail template")
+
+// TemplateData is the data available in the templates.
+type TemplateData struct {
+	Username string // Username is the username of the receiver.
+	Token    string // Token is the token of the mail, like the verification token.
+	AppURL   string // AppURL is the url of the app, the links of the mails point to it.
+	Locale   string // Locale is the locale the mail is rendered in.
+}
+
+// mailTemplate are the parsed templates of a mail type in a locale.
+type mailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// RenderedMail is a mail rendered from its templates.
+type RenderedMail struct {
+	Subject string // Subject is the subject of the mail.
+	Text    string // Text is the plain text body.
+	HTML    string // HTML is the html body.
+	Locale  string // Locale is the locale the mail was rendered in.
+}
+
+// Templates are the mail templates by locale and type.
+type Templates struct {
+	templates     map[string]map[string]*mailTemplate
+	defaultLocale string
+}
+
+// LoadTemplates parses every embedded template, so an invalid template stops the service at startup.
+// The default locale is read from MAIL_DEFAULT_LOCALE.
+func LoadTemplates() (*Templates, error) {
+	t := &Templates{templates: map[string]map[string]*mailTemplate{}, defaultLocale: defaultLocale}
+	if locale := os.Getenv("MAIL_DEFAULT_LOCALE"); locale != "" {
+		t.defaultLocale = strings.ToLower(locale)
+	}
+
+	files, err := fs.Glob(templateFiles, "templates/*/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		locale := path.Base(path.Dir(file))
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+		htmlFile := strings.TrimSuffix(file, ".txt") + ".html"
+
+		text, err := texttemplate.ParseFS(templateFiles, file)
+		if err != nil {
+			return nil, err
+		}
+
+		// the text file is parsed with the html too for the subject in the title of the layout
+		html, err := htmltemplate.ParseFS(templateFiles, "templates/layout.html", file, htmlFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if text.Lookup("subject") == nil || html.Lookup("content") == nil {
+			return nil, fmt.Errorf("%s must define a subject and %s a content", file, htmlFile)
+		}
+
+		if t.templates[locale] == nil {
+			t.templates[locale] = map[string]*mailTemplate{}
+		}
+
+		t.templates[locale][name] = &mailTemplate{text: text, html: html}
+	}
+
+	if len(t.templates[t.defaultLocale]) == 0 {
+		return nil, fmt.Errorf("there are no templates for the default locale %q", t.defaultLocale)
+	}
+
+	return t, nil
+}
+
+// lookup returns the templates of the type in the locale, the locale falls back to its language,
+// like es-CL to es, and then to the default locale.
+func (t *Templates) lookup(name, locale string) (*mailTemplate, string, bool) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	language, _, _ := strings.Cut(locale, "-")
+	for _, l := range []string{locale, language, t.defaultLocale} {
+		if tmpl, ok := t.templates[l][name]; ok {
+			return tmpl, l, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// Render renders the mail of the given type in the locale with the data.
+func (t *Templates) Render(name, locale string, data TemplateData) (*RenderedMail, error) {
+	tmpl, locale, ok := t.lookup(name, locale)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownTemplate, name)
+	}
+
+	data.Locale = locale
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	if err := tmpl.html.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return nil, err
+	}
+
+	return &RenderedMail{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+		Locale:  locale,
+	}, nil
+}
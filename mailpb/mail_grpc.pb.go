@@ -23,6 +23,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MailServiceClient interface {
 	SendMail(ctx context.Context, in *SendMailRequest, opts ...grpc.CallOption) (*SendMailResponse, error)
+	PreviewMail(ctx context.Context, in *PreviewMailRequest, opts ...grpc.CallOption) (*PreviewMailResponse, error)
+	RequeueMail(ctx context.Context, in *RequeueMailRequest, opts ...grpc.CallOption) (*RequeueMailResponse, error)
 }
 
 type mailServiceClient struct {
@@ -42,11 +44,31 @@ func (c *mailServiceClient) SendMail(ctx context.Context, in *SendMailRequest, o
 	return out, nil
 }
 
+func (c *mailServiceClient) PreviewMail(ctx context.Context, in *PreviewMailRequest, opts ...grpc.CallOption) (*PreviewMailResponse, error) {
+	out := new(PreviewMailResponse)
+	err := c.cc.Invoke(ctx, "/mailpb.MailService/PreviewMail", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailServiceClient) RequeueMail(ctx context.Context, in *RequeueMailRequest, opts ...grpc.CallOption) (*RequeueMailResponse, error) {
+	out := new(RequeueMailResponse)
+	err := c.cc.Invoke(ctx, "/mailpb.MailService/RequeueMail", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MailServiceServer is the server API for MailService service.
 // All implementations must embed UnimplementedMailServiceServer
 // for forward compatibility
 type MailServiceServer interface {
 	SendMail(context.Context, *SendMailRequest) (*SendMailResponse, error)
+	PreviewMail(context.Context, *PreviewMailRequest) (*PreviewMailResponse, error)
+	RequeueMail(context.Context, *RequeueMailRequest) (*RequeueMailResponse, error)
 	mustEmbedUnimplementedMailServiceServer()
 }
 
@@ -57,6 +79,12 @@ type UnimplementedMailServiceServer struct {
 func (UnimplementedMailServiceServer) SendMail(context.Context, *SendMailRequest) (*SendMailResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendMail not implemented")
 }
+func (UnimplementedMailServiceServer) PreviewMail(context.Context, *PreviewMailRequest) (*PreviewMailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PreviewMail not implemented")
+}
+func (UnimplementedMailServiceServer) RequeueMail(context.Context, *RequeueMailRequest) (*RequeueMailResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequeueMail not implemented")
+}
 func (UnimplementedMailServiceServer) mustEmbedUnimplementedMailServiceServer() {}
 
 // UnsafeMailServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +116,42 @@ func _MailService_SendMail_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MailService_PreviewMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreviewMailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).PreviewMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailpb.MailService/PreviewMail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).PreviewMail(ctx, req.(*PreviewMailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MailService_RequeueMail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequeueMailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailServiceServer).RequeueMail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mailpb.MailService/RequeueMail",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailServiceServer).RequeueMail(ctx, req.(*RequeueMailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MailService_ServiceDesc is the grpc.ServiceDesc for MailService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +163,14 @@ var MailService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendMail",
 			Handler:    _MailService_SendMail_Handler,
 		},
+		{
+			MethodName: "PreviewMail",
+			Handler:    _MailService_PreviewMail_Handler,
+		},
+		{
+			MethodName: "RequeueMail",
+			Handler:    _MailService_RequeueMail_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "mailpb/mail.proto",
@@ -3,39 +3,87 @@ package middlewares
 import (
 	"strings"
 
+	"github.com/DarioRoman01/photos/database"
 	"github.com/DarioRoman01/photos/models"
 	"github.com/DarioRoman01/photos/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
-var (
-	NO_AUTH_NEEDED = []string{
-		"login",
-		"signup",
-		"verify",
+// UserID returns the id of the authenticated user of the request, an empty id if the request was not
+// authenticated, which matches no rows and is denied by the authz package.
+func UserID(c *fiber.Ctx) string {
+	userID, _ := c.Locals("user_id").(string)
+	return userID
+}
+
+// Username returns the username of the authenticated user of the request, empty if the request was not authenticated.
+func Username(c *fiber.Ctx) string {
+	username, _ := c.Locals("username").(string)
+	return username
+}
+
+// requestToken returns the token of the request, the bearer token of the Authorization header is preferred over the cookie.
+func requestToken(c *fiber.Ctx) string {
+	auth := c.Get(fiber.HeaderAuthorization)
+	if scheme, token, ok := strings.Cut(auth, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
 	}
-)
 
-func shoulCheckToken(route string) bool {
-	for _, p := range NO_AUTH_NEEDED {
-		if strings.Contains(route, p) {
-			return false
-		}
+	return c.Cookies("token")
+}
+
+// routePath returns the path of the request as the routes match it, without case and trailing slashes,
+// so the checks of the paths can not be skipped writing the path in another way.
+func routePath(c *fiber.Ctx) string {
+	return strings.ToLower(strings.TrimRight(c.Path(), "/"))
+}
+
+// requiredScope returns the scope a personal access token needs for the request.
+func requiredScope(c *fiber.Ctx) models.TokenScope {
+	switch {
+	case strings.HasPrefix(routePath(c), "/images/upload"):
+		return models.UploadScope
+	case c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead:
+		return models.ReadScope
+	default:
+		return models.WriteScope
 	}
-	return true
 }
 
+// checkPersonalToken authenticates the request with a personal access token, the tokens can not be used
+// for the account routes and only for the requests allowed by their scopes.
+func checkPersonalToken(c *fiber.Ctx, raw string) error {
+	token, err := database.UsePersonalAccessToken(utils.HashToken(raw))
+	if err != nil {
+		return c.Status(401).JSON(utils.JsonError("Unauthorized"))
+	}
+
+	if strings.HasPrefix(routePath(c), "/users") {
+		return c.Status(403).JSON(utils.JsonError("Personal access tokens can not manage the account"))
+	}
+
+	if !token.HasScope(requiredScope(c)) {
+		return c.Status(403).JSON(utils.JsonError("Insufficient scope"))
+	}
+
+	c.Locals("user_id", token.UserID)
+	c.Locals("username", token.Username)
+	c.Locals("token_id", token.ID)
+	return c.Next()
+}
+
+// CheckAuthMiddleware authenticates every request, the public routes must be registered before it.
 func CheckAuthMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if !shoulCheckToken(c.Path()) {
-			return c.Next()
-		}
-
-		token := c.Cookies("token")
+		token := requestToken(c)
 		if token == "" {
 			return c.Status(401).JSON(utils.JsonError("Unauthorized"))
 		}
 
+		if strings.HasPrefix(token, models.PersonalTokenPrefix) {
+			return checkPersonalToken(c, token)
+		}
+
 		claims, err := utils.VerifyToken(token)
 		if err != nil {
 			return c.Status(401).JSON(utils.JsonError("Unauthorized"))
@@ -45,8 +93,14 @@ func CheckAuthMiddleware() fiber.Handler {
 			return c.Status(401).JSON(utils.JsonError("Invalid token"))
 		}
 
+		active, err := database.IsSessionActive(claims.SessionID)
+		if err != nil || !active {
+			return c.Status(401).JSON(utils.JsonError("Unauthorized"))
+		}
+
 		c.Locals("user_id", claims.UserID)
 		c.Locals("username", claims.Username)
+		c.Locals("session_id", claims.SessionID)
 		return c.Next()
 	}
 }
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// isWrite checks if the request changes data.
+func isWrite(c *fiber.Ctx) bool {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// VERIFICATION_ACTIONS are the actions that can require a verified email, by name.
+var VERIFICATION_ACTIONS = map[string]func(c *fiber.Ctx) bool{
+	// upload are the uploads, including the resumable ones.
+	"upload": func(c *fiber.Ctx) bool {
+		return isWrite(c) && strings.HasPrefix(routePath(c), "/images/upload")
+	},
+	// share is the creation of share links.
+	"share": func(c *fiber.Ctx) bool {
+		return c.Method() == fiber.MethodPost && routePath(c) == "/shares"
+	},
+	// write are the write requests except the account management, so the user can still verify the email.
+	"write": func(c *fiber.Ctx) bool {
+		return isWrite(c) && !strings.HasPrefix(routePath(c), "/users")
+	},
+}
+
+// RequireVerifiedMiddleware rejects the requests of the users that did not verify their email for the actions
+// listed in the VERIFICATION_REQUIRED env variable, upload and share by default or none to allow every action.
+// It must be used after CheckAuthMiddleware.
+func RequireVerifiedMiddleware() (fiber.Handler, error) {
+	policy := os.Getenv("VERIFICATION_REQUIRED")
+	if policy == "" {
+		policy = "upload,share"
+	}
+
+	actions := []func(c *fiber.Ctx) bool{}
+	for _, name := range strings.Split(policy, ",") {
+		name = strings.TrimSpace(name)
+		if name == "none" {
+			continue
+		}
+
+		action, ok := VERIFICATION_ACTIONS[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown VERIFICATION_REQUIRED action %q", name)
+		}
+
+		actions = append(actions, action)
+	}
+
+	return func(c *fiber.Ctx) error {
+		userID := UserID(c)
+		if userID == "" {
+			return c.Status(401).JSON(utils.JsonError("Unauthorized"))
+		}
+
+		for _, action := range actions {
+			if !action(c) {
+				continue
+			}
+
+			user, err := database.GetUserByID(userID)
+			if err != nil {
+				return c.Status(401).JSON(utils.JsonError("Unauthorized"))
+			}
+
+			if !user.IsVerified {
+				return c.Status(403).JSON(utils.JsonError("Email verification required"))
+			}
+
+			break
+		}
+
+		return c.Next()
+	}, nil
+}
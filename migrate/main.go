@@ -0,0 +1,89 @@
+// migrate is a command that applies, reverts and shows the database schema migrations
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DarioRoman01/photos/database"
+)
+
+const usage = `usage: migrate <command> [steps]
+
+commands:
+  up [steps]    apply the pending migrations, all of them when steps is not given
+  down [steps]  revert the applied migrations, only the last one when steps is not given
+  status        show the applied and pending migrations`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	steps := 0
+	if len(os.Args) > 2 {
+		n, err := strconv.Atoi(os.Args[2])
+		if err != nil || n < 1 {
+			log.Fatalf("Invalid steps: %s", os.Args[2])
+		}
+
+		steps = n
+	}
+
+	repo, err := database.NewPostgresRepository(os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		log.Fatalf("Error connecting to the database: %v", err)
+	}
+
+	migrator, err := repo.Migrator()
+	if err != nil {
+		log.Fatalf("Error loading migrations: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		migrations, err := migrator.Up(steps)
+		for _, m := range migrations {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+
+		migrations, err := migrator.Down(steps)
+		for _, m := range migrations {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+
+		if err != nil {
+			log.Fatalf("Error reverting migrations: %v", err)
+		}
+
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Error getting migrations status: %v", err)
+		}
+
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, appliedAt)
+		}
+
+	default:
+		log.Fatal(usage)
+	}
+}
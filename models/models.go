@@ -2,6 +2,7 @@ package models
 
 import (
 	"mime/multipart"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -16,6 +17,10 @@ const (
 	VerifyToken
 	// ChangePasswordToken is the type of change password token
 	ChangePasswordToken
+	// TwoFactorToken is the type of the intermediate token of a login that requires a TOTP code
+	TwoFactorToken
+	// OIDCStateToken is the type of the token that keeps the state of a login with an identity provider
+	OIDCStateToken
 )
 
 // String returns the string representation of the token type
@@ -27,6 +32,10 @@ func (t TokenType) String() string {
 		return "verification"
 	case ChangePasswordToken:
 		return "changepassword"
+	case TwoFactorToken:
+		return "twofactor"
+	case OIDCStateToken:
+		return "oidcstate"
 	default:
 		return "unknown"
 	}
@@ -34,12 +43,23 @@ func (t TokenType) String() string {
 
 // User represents a user account in the system.
 type User struct {
-	ID         string `json:"id"`          // ID is unique identifier for the user.
-	Username   string `json:"username"`    // Username is the user's username.
-	Email      string `json:"email"`       // Email is the user's email address.
-	Password   string `json:"-"`           // Password is the user's password, hashed.
-	CreatedAt  string `json:"created_at"`  // CreatedAt is the time the user was created.
-	IsVerified bool   `json:"is_verified"` // IsVerified is true if the user has verified their email address.
+	ID          string `json:"id"`           // ID is unique identifier for the user.
+	Username    string `json:"username"`     // Username is the user's username.
+	Email       string `json:"email"`        // Email is the user's email address.
+	Password    string `json:"-"`            // Password is the user's password, hashed.
+	CreatedAt   string `json:"created_at"`   // CreatedAt is the time the user was created.
+	IsVerified  bool   `json:"is_verified"`  // IsVerified is true if the user has verified their email address.
+	TOTPSecret  string `json:"-"`            // TOTPSecret is the base32 secret of the user's authenticator app, empty if not enrolled.
+	TOTPEnabled bool   `json:"totp_enabled"` // TOTPEnabled is true if the login requires a TOTP code.
+}
+
+// Identity represents the account of a user in an external identity provider.
+type Identity struct {
+	Issuer    string `json:"issuer"`     // Issuer is the url of the identity provider.
+	Subject   string `json:"subject"`    // Subject is the id of the user in the identity provider.
+	UserID    string `json:"user_id"`    // UserID is the ID of the linked user.
+	Email     string `json:"email"`      // Email is the verified email of the user in the identity provider when it was linked.
+	CreatedAt string `json:"created_at"` // CreatedAt is the time the identity was linked.
 }
 
 // UserLoginRegisters represents a user login or registration request.
@@ -47,39 +67,319 @@ type UserLoginRegister struct {
 	Username string `json:"username"` // Username is the user's username.
 	Email    string `json:"email"`    // Email is the user's email address.
 	Password string `json:"password"` // Password is the user's password, plaintext.
+	Device   string `json:"device"`   // Device is the optional name of the device that logs in.
+}
+
+// MailStatus is the delivery status of a mail in the outbox.
+type MailStatus string
+
+const (
+	// MailPending is the status of a mail waiting to be sent, also after a failed attempt.
+	MailPending MailStatus = "pending"
+	// MailSending is the status of a mail claimed by a worker, it is sent again if the worker does not finish in time.
+	MailSending MailStatus = "sending"
+	// MailSent is the status of a delivered mail.
+	MailSent MailStatus = "sent"
+	// MailDead is the status of a mail that failed too many times, it is only sent again if it is requeued.
+	MailDead MailStatus = "dead"
+)
+
+// OutboxMail represents a mail waiting in the outbox to be sent by the mail service.
+type OutboxMail struct {
+	ID        string     // ID is unique identifier for the mail.
+	Type      string     // Type is the type of the mail, the name of its templates.
+	Receiver  string     // Receiver is the email address of the receiver.
+	Username  string     // Username is the username of the receiver.
+	Token     string     // Token is the token of the mail, removed once the mail is sent.
+	Locale    string     // Locale is the preferred locale of the receiver.
+	Status    MailStatus // Status is the delivery status of the mail.
+	Attempts  int        // Attempts is the number of times the mail was claimed to be sent.
+	LastError string     // LastError is the error of the last failed attempt.
+}
+
+// Session represents a login of a user on a device, it lasts while its refresh tokens are renewed.
+type Session struct {
+	ID         string `json:"id"`           // ID is unique identifier for the session.
+	UserID     string `json:"user_id"`      // UserID is the ID of the user who logged in.
+	Username   string `json:"-"`            // Username is the user's username.
+	Device     string `json:"device"`       // Device is the name of the device, the user agent if the client did not send one.
+	IP         string `json:"ip"`           // IP is the address the session was created from.
+	CreatedAt  string `json:"created_at"`   // CreatedAt is the time the user logged in.
+	LastUsedAt string `json:"last_used_at"` // LastUsedAt is the time the session was last refreshed.
+	Current    bool   `json:"current"`      // Current is true for the session of the request.
 }
 
+// TokenScope is a permission granted to a personal access token.
+type TokenScope string
+
+const (
+	// ReadScope allows the read requests, served by the query service.
+	ReadScope TokenScope = "read"
+	// UploadScope allows the uploads, including the resumable ones.
+	UploadScope TokenScope = "upload"
+	// WriteScope allows every write request except the account management.
+	WriteScope TokenScope = "write"
+)
+
+// TokenScopes are the known personal access token scopes.
+var TokenScopes = []TokenScope{ReadScope, UploadScope, WriteScope}
+
+// PersonalTokenPrefix is the prefix of every personal access token, it tells them apart from the jwt access tokens.
+const PersonalTokenPrefix = "pat_"
+
+// PersonalAccessToken represents a named long lived token used by scripts and apps instead of a session.
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`              // ID is unique identifier for the token.
+	UserID     string     `json:"user_id"`         // UserID is the ID of the user who owns the token.
+	Username   string     `json:"-"`               // Username is the username of the user who owns the token.
+	Name       string     `json:"name"`            // Name describes what the token is used for.
+	Token      string     `json:"token,omitempty"` // Token is the plaintext token, only returned when the token is created.
+	Scopes     []string   `json:"scopes"`          // Scopes are the permissions granted to the token.
+	CreatedAt  string     `json:"created_at"`      // CreatedAt is the time the token was created.
+	LastUsedAt *time.Time `json:"last_used_at"`    // LastUsedAt is the time the token was last used, nil if it was never used.
+	ExpiresAt  *time.Time `json:"expires_at"`      // ExpiresAt is the time the token expires, nil if it never expires.
+}
+
+// HasScope checks if the token was granted the given scope.
+func (t *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.Scopes {
+		if TokenScope(s) == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreatePersonalAccessTokenRequest represents a request to create a personal access token.
+type CreatePersonalAccessTokenRequest struct {
+	Name      string     `json:"name"`       // Name describes what the token is used for.
+	Scopes    []string   `json:"scopes"`     // Scopes are the permissions granted to the token.
+	ExpiresAt *time.Time `json:"expires_at"` // ExpiresAt is the optional time the token expires.
+}
+
+// TwoFactorRequest represents a request with a TOTP code or a recovery code.
+type TwoFactorRequest struct {
+	Token  string `json:"token"`  // Token is the intermediate token returned by the login, only for the second login step.
+	Code   string `json:"code"`   // Code is the TOTP code of the authenticator app or a recovery code.
+	Device string `json:"device"` // Device is the optional name of the device that logs in.
+}
+
+// RefreshRequest represents a request to renew the access token of a session.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"` // RefreshToken is the refresh token, read from the cookie if empty.
+}
+
+// ResendVerificationRequest represents a request to receive the verification mail again.
+type ResendVerificationRequest struct {
+	Email string `json:"email"` // Email is the user's email address.
+}
+
+// ForgotPasswordRequest represents a request to receive a password reset mail.
+type ForgotPasswordRequest struct {
+	Email string `json:"email"` // Email is the user's email address.
+}
+
+// ResetPasswordRequest represents a request to change the password with a reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`    // Token is the change password token sent by mail.
+	Password string `json:"password"` // Password is the new password, plaintext.
+}
+
+const (
+	// ThumbnailVariant is the name of the small resized copy of an image.
+	ThumbnailVariant = "thumbnail"
+	// PreviewVariant is the name of the medium resized copy of an image.
+	PreviewVariant = "preview"
+)
+
+// ImageVariants are the names of the resized copies generated for every uploaded image.
+var ImageVariants = []string{ThumbnailVariant, PreviewVariant}
+
 // Image represents an image in the bucket.
 type Image struct {
-	ID        string `json:"id"`         // ID is unique identifier for the image.
-	Name      string `json:"name"`       // Name is the image's name.
-	URL       string `json:"url"`        // URL is the image's URL.
-	UserID    string `json:"user_id"`    // UserID is the ID of the user who uploaded the image.
-	FolderID  string `json:"folder_id"`  // FolderID is the ID of the folder the image is in.
-	CreatedAt string `json:"created_at"` // CreatedAt is the time the image was created.
+	ID           string        `json:"id"`            // ID is unique identifier for the image.
+	Name         string        `json:"name"`          // Name is the image's name.
+	ObjectName   string        `json:"-"`             // ObjectName is the name of the image's file in the bucket, unique per image.
+	URL          string        `json:"url"`           // URL is the image's URL.
+	ThumbnailURL string        `json:"thumbnail_url"` // ThumbnailURL is the URL of the image's thumbnail.
+	PreviewURL   string        `json:"preview_url"`   // PreviewURL is the URL of the image's preview.
+	UserID       string        `json:"user_id"`       // UserID is the ID of the user who uploaded the image.
+	FolderID     string        `json:"folder_id"`     // FolderID is the ID of the folder the image is in.
+	CreatedAt    string        `json:"created_at"`    // CreatedAt is the time the image was created.
+	DeletedAt    *time.Time    `json:"deleted_at"`    // DeletedAt is the time the image was moved to the trash, nil if it is not in the trash.
+	Metadata     ImageMetadata `json:"metadata"`      // Metadata is the metadata read from the image file.
+}
+
+// ImageMetadata represents the metadata read from an image file, fields are nil when the file does not have them.
+type ImageMetadata struct {
+	TakenAt      *time.Time `json:"taken_at"`      // TakenAt is the time the photo was taken.
+	CameraMake   *string    `json:"camera_make"`   // CameraMake is the manufacturer of the camera.
+	CameraModel  *string    `json:"camera_model"`  // CameraModel is the model of the camera.
+	Lens         *string    `json:"lens"`          // Lens is the model of the lens.
+	ExposureTime *string    `json:"exposure_time"` // ExposureTime is the exposure time in seconds, e.g. "1/250".
+	FNumber      *float64   `json:"f_number"`      // FNumber is the aperture of the lens.
+	ISO          *int       `json:"iso"`           // ISO is the ISO speed rating.
+	Width        *int       `json:"width"`         // Width is the width of the image in pixels.
+	Height       *int       `json:"height"`        // Height is the height of the image in pixels.
+	Orientation  *int       `json:"orientation"`   // Orientation is the exif orientation of the image.
+	Latitude     *float64   `json:"latitude"`      // Latitude is the latitude where the photo was taken.
+	Longitude    *float64   `json:"longitude"`     // Longitude is the longitude where the photo was taken.
 }
 
 // Folder represents a folder in the bucket.
 type Folder struct {
-	ID        string  `json:"id"`         // ID is unique identifier for the folder.
-	Name      string  `json:"name"`       // Name is the folder's name.
-	UserID    string  `json:"user_id"`    // UserID is the ID of the user who uploaded the image.
-	CreatedAt string  `json:"created_at"` // CreatedAt is the time the folder was created.
-	Images    []Image `json:"images"`     // Images is the images in the folder.
+	ID        string     `json:"id"`         // ID is unique identifier for the folder.
+	Name      string     `json:"name"`       // Name is the folder's name.
+	ParentID  *string    `json:"parent_id"`  // ParentID is the ID of the folder this folder is in, nil for the folders in the root.
+	Path      string     `json:"path"`       // Path is the names of the folder and its ancestors from the root, like a/b/c.
+	UserID    string     `json:"user_id"`    // UserID is the ID of the user who uploaded the image.
+	CreatedAt string     `json:"created_at"` // CreatedAt is the time the folder was created.
+	DeletedAt *time.Time `json:"deleted_at"` // DeletedAt is the time the folder was moved to the trash, nil if it is not in the trash.
+	Images    []Image    `json:"images"`     // Images is the images in the folder.
+}
+
+// DeletedFolder is a folder deleted with its descendants, with the data needed to delete their objects.
+type DeletedFolder struct {
+	Folder       *Folder         // Folder is the deleted folder.
+	Folders      int             // Folders is the number of deleted folders, the folder and its descendants.
+	Images       []*TrashedImage // Images are the deleted images of the folder and its descendants.
+	SharedPrefix bool            // SharedPrefix is true if other folders have objects under the path of the folder.
+}
+
+// RelocationStatus is the step of a folder relocation.
+type RelocationStatus string
+
+const (
+	// RelocationCopying is the status of a relocation copying the objects to the new prefix, the folder is not moved
+	// yet so an interrupted relocation is rolled back.
+	RelocationCopying RelocationStatus = "copying"
+	// RelocationCommitted is the status of a relocation whose folder was moved, the objects of the old prefix are
+	// deleted and an interrupted relocation is completed.
+	RelocationCommitted RelocationStatus = "committed"
+	// RelocationAborted is the status of a failed relocation whose copies are deleted, the folder is not moved.
+	RelocationAborted RelocationStatus = "aborted"
+)
+
+// FolderRelocation is a rename or a move of a folder in progress, it is journaled in the database so an
+// interrupted relocation is recovered.
+type FolderRelocation struct {
+	ID       string           // ID is unique identifier for the relocation.
+	UserID   string           // UserID is the ID of the owner of the folder.
+	Username string           // Username is the username of the owner, the first segment of the objects keys.
+	FolderID string           // FolderID is the ID of the relocated folder.
+	ParentID *string          // ParentID is the ID of the new parent of the folder, nil for the root.
+	Name     string           // Name is the new name of the folder.
+	OldPath  string           // OldPath is the path of the folder before the relocation.
+	NewPath  string           // NewPath is the path of the folder after the relocation.
+	Status   RelocationStatus // Status is the step of the relocation.
+	LeaseID  string           // LeaseID identifies the worker running the relocation, it changes when other worker recovers it.
+}
+
+// MoveFolderRequest represents a request to rename a folder or to move it into other folder.
+type MoveFolderRequest struct {
+	Name     string  `json:"name"`      // Name is the new name of the folder, the name is kept if it is empty.
+	ParentID *string `json:"parent_id"` // ParentID is the ID of the new parent, empty for the root and nil to keep the parent.
+}
+
+// Album represents a collection of images of a user, an image can be in many albums and the albums do not
+// depend on the folders of the images.
+type Album struct {
+	ID           string  `json:"id"`             // ID is unique identifier for the album.
+	Name         string  `json:"name"`           // Name is the album's name.
+	UserID       string  `json:"user_id"`        // UserID is the ID of the user who created the album.
+	CoverImageID *string `json:"cover_image_id"` // CoverImageID is the ID of the image chosen as cover, nil to use the first image.
+	Cover        *Image  `json:"cover"`          // Cover is the chosen cover or the first image of the album, nil if the album is empty.
+	ImageCount   int     `json:"image_count"`    // ImageCount is the number of images in the album, the ones in the trash are not counted.
+	CreatedAt    string  `json:"created_at"`     // CreatedAt is the time the album was created.
+}
+
+// UpdateAlbumRequest represents a request to rename an album or to change its cover.
+type UpdateAlbumRequest struct {
+	Name         string  `json:"name"`           // Name is the new name of the album, the name is kept if it is empty.
+	CoverImageID *string `json:"cover_image_id"` // CoverImageID is the ID of the new cover, empty to use the first image and nil to keep it.
+}
+
+// AlbumImagesRequest represents a request to add images to an album or to reorder its images.
+type AlbumImagesRequest struct {
+	ImageIDs []string `json:"image_ids"` // ImageIDs are the IDs of the images, in the order they must have in the album.
+}
+
+// AlbumCollaboratorRequest represents a request to share an album with a user.
+type AlbumCollaboratorRequest struct {
+	Role string `json:"role"` // Role is the role of the user in the album, viewer or contributor.
+}
+
+// TrashedImage represents an image in the trash with the data needed to find its files in the bucket.
+type TrashedImage struct {
+	Image
+	Username   string // Username is the username of the owner of the image.
+	FolderName string // FolderName is the path of the folder the image is in.
+}
+
+// ShareLink represents a public link to an image or a folder.
+type ShareLink struct {
+	ID          string     `json:"id"`           // ID is unique identifier for the link.
+	Token       string     `json:"token"`        // Token is the random secret in the link url.
+	UserID      string     `json:"user_id"`      // UserID is the ID of the user who shares the image or folder.
+	ImageID     *string    `json:"image_id"`     // ImageID is the ID of the shared image, nil if a folder is shared.
+	FolderID    *string    `json:"folder_id"`    // FolderID is the ID of the shared folder, nil if an image is shared.
+	Password    string     `json:"-"`            // Password is the link's password, hashed, empty if the link has no password.
+	HasPassword bool       `json:"has_password"` // HasPassword is true if the link requires a password.
+	ExpiresAt   *time.Time `json:"expires_at"`   // ExpiresAt is the time the link expires, nil if it never expires.
+	Views       int64      `json:"views"`        // Views is the number of times the link was opened.
+	RevokedAt   *time.Time `json:"revoked_at"`   // RevokedAt is the time the link was revoked, nil if it is active.
+	CreatedAt   string     `json:"created_at"`   // CreatedAt is the time the link was created.
+}
+
+// CreateShareLinkRequest represents a request to share an image or a folder.
+type CreateShareLinkRequest struct {
+	ImageID   string     `json:"image_id"`   // ImageID is the ID of the image to share.
+	FolderID  string     `json:"folder_id"`  // FolderID is the ID of the folder to share.
+	Password  string     `json:"password"`   // Password is the optional password of the link, plaintext.
+	ExpiresAt *time.Time `json:"expires_at"` // ExpiresAt is the optional time the link expires.
+}
+
+// SortOrder is the order of the results of a list request.
+type SortOrder string
+
+const (
+	// Descending sorts the results from the newest to the oldest.
+	Descending SortOrder = "desc"
+	// Ascending sorts the results from the oldest to the newest.
+	Ascending SortOrder = "asc"
+)
+
+// PageRequest represents the pagination parameters of a list request.
+type PageRequest struct {
+	Cursor string    // Cursor is the opaque cursor returned with the previous page, empty for the first page.
+	Limit  int       // Limit is the maximum number of results of the page.
+	Order  SortOrder // Order is the order of the results by creation time.
 }
 
 // Claims represents the claims in a JWT.
 type Claims struct {
-	Username             string `json:"username"` // Username is the user's username.
-	UserID               string `json:"user_id"`  // UserID is the ID of the user who uploaded the image.
+	Username             string `json:"username"`      // Username is the user's username.
+	UserID               string `json:"user_id"`       // UserID is the ID of the user who uploaded the image.
+	Type                 string `json:"type"`          // Type is the type of token.
+	SessionID            string `json:"sid,omitempty"` // SessionID is the ID of the session of an access token.
+	jwt.RegisteredClaims        // RegisteredClaims are the registered claims in a JWT.
+}
+
+// OIDCStateClaims represents the claims of the token that keeps the state of a login with an identity provider
+// between the redirect to the provider and its callback.
+type OIDCStateClaims struct {
 	Type                 string `json:"type"`     // Type is the type of token.
+	State                string `json:"state"`    // State is the state sent to the provider, it must be returned in the callback.
+	Nonce                string `json:"nonce"`    // Nonce is the nonce sent to the provider, it must be in the id token.
+	Verifier             string `json:"verifier"` // Verifier is the PKCE code verifier of the login.
 	jwt.RegisteredClaims        // RegisteredClaims are the registered claims in a JWT.
 }
 
 // UploadRequest represents a request to upload an image.
 type UploadRequest struct {
 	FolderID   string         // FolderID is the ID of the folder the image is in.
-	FolderName string         // FolderName is the name of the folder to upload to.
+	FolderName string         // FolderName is the path of the folder to upload to, like a/b/c.
 	Filename   string         // Filename is the name of the file to upload.
 	Username   string         // Username is the user's username.
 	UserID     string         // UserID is the ID of the user who uploaded the image.
@@ -87,8 +387,6 @@ type UploadRequest struct {
 }
 
 type MoveFileRequest struct {
-	FolderName    string `json:"folder_name"`     // FolderName is the name of the folder where the file is store.
-	NewFolderName string `json:"new_folder_name"` // NewFolderName is the name of the folder where the file will be moved to.
-	Filename      string `json:"filename"`        // Filename is the name of the file to move.
-	FileID        string `json:"file_id"`         // FileID is the ID of the file to move.
+	NewFolderName string `json:"new_folder_name"` // NewFolderName is the path of the folder where the file will be moved to, missing folders are created.
+	FileID        string `json:"file_id"`         // FileID is the ID of the file to move, its folder and name are read from the image.
 }
@@ -0,0 +1,86 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// signingMethods are the accepted algorithms of the id tokens, HMAC is never accepted
+// because the client secret is not a key of the provider.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// flexibleBool is a boolean claim that some providers send as a string.
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseBool(strings.Trim(string(data), `"`))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+
+	*b = flexibleBool(v)
+	return nil
+}
+
+// IDToken are the claims of a verified id token.
+type IDToken struct {
+	Email             string       `json:"email"`              // Email is the email of the user.
+	EmailVerified     flexibleBool `json:"email_verified"`     // EmailVerified is true if the provider verified the email.
+	Name              string       `json:"name"`               // Name is the full name of the user.
+	PreferredUsername string       `json:"preferred_username"` // PreferredUsername is the username of the user in the provider.
+	Nonce             string       `json:"nonce"`              // Nonce is the nonce sent in the authorization request.
+	AuthorizedParty   string       `json:"azp"`                // AuthorizedParty is the client the token was issued to.
+	jwt.RegisteredClaims
+}
+
+// verifyIDToken verifies the signature and the claims of an id token issued to the client
+// for the authorization request with the given nonce.
+func (p *Provider) verifyIDToken(ctx context.Context, m *metadata, raw, nonce string) (*IDToken, error) {
+	claims := new(IDToken)
+	parser := &jwt.Parser{ValidMethods: signingMethods}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.get(ctx, m.JWKSURI, kid)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("id token has no expiration")
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("id token issuer %q does not match", claims.Issuer)
+	}
+
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("id token was not issued to the client")
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, errors.New("id token was not authorized for the client")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	return claims, nil
+}
+
+// Issuer returns the issuer of the provider, the identities are unique by issuer and subject.
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
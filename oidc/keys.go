@@ -0,0 +1,155 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keysRefreshInterval is the minimum time between two fetches of the provider keys, the keys are
+// fetched again when a token is signed with an unknown key so rotated keys are picked up.
+const keysRefreshInterval = time.Minute
+
+// jwk is a public key in the JSON Web Key format.
+type jwk struct {
+	Kty string `json:"kty"` // Kty is the key type, RSA, EC or OKP.
+	Kid string `json:"kid"` // Kid is the id of the key.
+	Use string `json:"use"` // Use is the usage of the key, keys that are not for signatures are ignored.
+	Crv string `json:"crv"` // Crv is the curve of an EC or OKP key.
+	X   string `json:"x"`   // X is the x coordinate of an EC key or the public key of an OKP key.
+	Y   string `json:"y"`   // Y is the y coordinate of an EC key.
+	N   string `json:"n"`   // N is the modulus of an RSA key.
+	E   string `json:"e"`   // E is the exponent of an RSA key.
+}
+
+// publicKey parses the public key of the jwk.
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("invalid %s key", k.Crv)
+		}
+
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// keyCache caches the signing keys of the provider by id.
+type keyCache struct {
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey // keys are the public keys by id.
+	fetchedAt time.Time                   // fetchedAt is the time the keys were fetched.
+}
+
+func newKeyCache(client *http.Client) *keyCache {
+	return &keyCache{client: client, keys: map[string]crypto.PublicKey{}}
+}
+
+// get returns the key with the given id, the keys are fetched again if the id is unknown.
+func (c *keyCache) get(ctx context.Context, uri, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(c.fetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+
+	if err := getJSON(ctx, c.client, uri, &set); err != nil {
+		return nil, fmt.Errorf("fetching provider keys: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			log.Printf("Ignoring provider key %q: %v", k.Kid, err)
+			continue
+		}
+
+		keys[k.Kid] = key
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
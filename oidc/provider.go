@@ -0,0 +1,220 @@
+// Package oidc implements the authorization code flow with PKCE of OpenID Connect, so the users can
+// login with an external identity provider.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// discoveryPath is the path of the provider metadata, relative to the issuer.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// ErrNotConfigured is returned when the OIDC_ISSUER env variable is not set.
+var ErrNotConfigured = errors.New("oidc login is not configured")
+
+// Config is the configuration of the client registered in the provider.
+type Config struct {
+	Issuer       string   // Issuer is the url of the provider, its metadata is discovered from it.
+	ClientID     string   // ClientID is the id of the client.
+	ClientSecret string   // ClientSecret is the secret of a confidential client, empty for a public client.
+	RedirectURL  string   // RedirectURL is the url of the callback, it must be registered in the provider.
+	Scopes       []string // Scopes are the requested scopes, openid is always requested.
+}
+
+// ConfigFromEnv reads the configuration from the OIDC_* env variables, ErrNotConfigured is returned
+// if OIDC_ISSUER is not set.
+func ConfigFromEnv() (*Config, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, ErrNotConfigured
+	}
+
+	config := &Config{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required")
+	}
+
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		config.Scopes = append([]string{"openid"}, strings.Fields(scopes)...)
+	}
+
+	return config, nil
+}
+
+// metadata are the provider endpoints published in its discovery document.
+type metadata struct {
+	Issuer                string `json:"issuer"`                 // Issuer must be the configured issuer.
+	AuthorizationEndpoint string `json:"authorization_endpoint"` // AuthorizationEndpoint is where the user logs in.
+	TokenEndpoint         string `json:"token_endpoint"`         // TokenEndpoint exchanges the code for the tokens.
+	JWKSURI               string `json:"jwks_uri"`               // JWKSURI is the url of the keys that sign the id tokens.
+}
+
+// Provider is an OpenID Connect provider, its metadata is discovered on the first use so the
+// service starts even if the provider is not reachable.
+type Provider struct {
+	config *Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata // metadata is nil until the discovery succeeds.
+	keys     *keyCache // keys caches the signing keys of the provider.
+}
+
+// NewProvider returns the provider of the given configuration.
+func NewProvider(config *Config) *Provider {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &Provider{config: config, client: client, keys: newKeyCache(client)}
+}
+
+// discover returns the metadata of the provider, fetching it the first time.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	m := new(metadata)
+	if err := getJSON(ctx, p.client, p.config.Issuer+discoveryPath, m); err != nil {
+		return nil, fmt.Errorf("discovering provider: %w", err)
+	}
+
+	if strings.TrimSuffix(m.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("discovered issuer %q does not match %q", m.Issuer, p.config.Issuer)
+	}
+
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("provider metadata is missing endpoints")
+	}
+
+	p.metadata = m
+	return m, nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the url of the provider where the user is redirected to login.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// tokenResponse is the response of the token endpoint.
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`          // IDToken is the signed id token.
+	Error            string `json:"error"`             // Error is the error code of a failed exchange.
+	ErrorDescription string `json:"error_description"` // ErrorDescription describes the error.
+}
+
+// Exchange exchanges the authorization code for the tokens and returns the verified claims of the id token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDToken, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	tokens := new(tokenResponse)
+	if err := json.NewDecoder(res.Body).Decode(tokens); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s %s", res.StatusCode, tokens.Error, tokens.ErrorDescription)
+	}
+
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, m, tokens.IDToken, nonce)
+}
+
+// getJSON decodes the json response of a GET request.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", url, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAlbumsHandler returns a page of the albums of the user with their covers.
+func (s *QueryService) GetAlbumsHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
+	}
+
+	userID := middlewares.UserID(c)
+	albums, nextCursor, err := database.GetAlbums(userID, page)
+	if err != nil {
+		return listError(c, err, "Error getting albums")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"albums":     albums,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
+
+// GetAlbumHandler returns an album with its cover and a page of its images, the images are in the order
+// of the album unless the order is descending.
+func (s *QueryService) GetAlbumHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
+	}
+
+	if c.Query("order") == "" {
+		page.Order = models.Ascending
+	}
+
+	album, err := authz.AuthorizeAlbum(middlewares.UserID(c), c.Params("albumID"), authz.Read)
+	if err != nil {
+		return notFoundError(c, err, "Album not found", "Error getting album")
+	}
+
+	images, nextCursor, err := database.GetAlbumImages(album.ID, page)
+	if err != nil {
+		return listError(c, err, "Error getting images")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"album":      album,
+		"images":     images,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// notFoundError returns the response for an error of an authorized access, the denied accesses
+// are returned as 404 and any other error as 500.
+func notFoundError(c *fiber.Ctx, err error, notFound, message string) error {
+	if errors.Is(err, authz.ErrNotFound) {
+		return c.Status(404).JSON(utils.JsonError(notFound))
+	}
+
+	return c.Status(500).JSON(utils.JsonError(message))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"path"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/bucket"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// fileAuthMiddleware authenticates the file requests, the requests with the share query param are
+// authorized by their share link instead.
+func fileAuthMiddleware() fiber.Handler {
+	checkAuth := middlewares.CheckAuthMiddleware()
+	return func(c *fiber.Ctx) error {
+		if c.Query("share") != "" {
+			return c.Next()
+		}
+
+		return checkAuth(c)
+	}
+}
+
+// fileSubject returns the subject of a file request, the holder of the share link of the share query param
+// or the authenticated user. authz.ErrNotFound is returned for an unknown link or a wrong password.
+func fileSubject(c *fiber.Ctx) (authz.Subject, error) {
+	token := c.Query("share")
+	if token == "" {
+		return authz.User(middlewares.UserID(c)), nil
+	}
+
+	link, err := database.GetShareLinkByToken(token)
+	if err != nil {
+		return authz.Subject{}, authz.ErrNotFound
+	}
+
+	if link.HasPassword {
+		ok, _ := utils.ComparePasswords(c.Get(sharePasswordHeader), link.Password)
+		if !ok {
+			return authz.Subject{}, authz.ErrNotFound
+		}
+	}
+
+	return authz.Link(link), nil
+}
+
+// GetFileHandler serves a file of the local bucket to the users who can read its image, the images in the trash
+// and the files that are not of an image are not served.
+func (s *QueryService) GetFileHandler(c *fiber.Ctx) error {
+	key, err := url.PathUnescape(c.Params("*"))
+	if err != nil || !bucket.ValidKey(key) {
+		return c.Status(404).JSON(utils.JsonError("File not found"))
+	}
+
+	subject, err := fileSubject(c)
+	if err != nil {
+		return c.Status(404).JSON(utils.JsonError("File not found"))
+	}
+
+	image, err := database.GetImageByURL(bucket.URL(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = authz.ErrNotFound
+	}
+
+	if err == nil {
+		err = authz.Authorize(subject, authz.Read, authz.Image(image))
+	}
+
+	if err != nil {
+		return notFoundError(c, err, "File not found", "Error getting file")
+	}
+
+	file, err := bucket.OpenServedFile(key)
+	if err != nil {
+		return c.Status(404).JSON(utils.JsonError("File not found"))
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return c.Status(500).JSON(utils.JsonError("Error reading file"))
+	}
+
+	// the file is closed once the response is sent
+	c.Type(path.Ext(key))
+	return c.SendStream(file, int(info.Size()))
+}
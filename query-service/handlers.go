@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"strconv"
 
+	"github.com/DarioRoman01/photos/authz"
 	"github.com/DarioRoman01/photos/bucket"
 	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
 	"github.com/DarioRoman01/photos/utils"
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,7 +17,11 @@ import (
 type QueryService struct{}
 
 func NewQueryService() (*QueryService, error) {
-	s3repo, err := bucket.NewS3BucketRepository()
+	if err := utils.LoadKeys(); err != nil {
+		return nil, err
+	}
+
+	bucketRepo, err := bucket.NewBucketRepository()
 	if err != nil {
 		return nil, err
 	}
@@ -24,90 +32,188 @@ func NewQueryService() (*QueryService, error) {
 	}
 
 	database.SetDatabaseRepository(postgresRepo)
-	bucket.SetBucketRepository(s3repo)
+	bucket.SetBucketRepository(bucketRepo)
+	authz.SetRelations(authz.DatabaseRelations())
 	return &QueryService{}, nil
 }
 
-func (s *QueryService) GetImagesHandler(c *fiber.Ctx) error {
-	cursor := c.Query("cursor")
-	strLimit := c.Query("limit")
-	if strLimit == "" {
-		strLimit = "10"
+// parsePage reads the pagination parameters of the request.
+func parsePage(c *fiber.Ctx) (*models.PageRequest, error) {
+	limit, err := strconv.Atoi(c.Query("limit", "10"))
+	if err != nil {
+		return nil, errors.New("Error parsing limit")
+	}
+
+	order := models.SortOrder(c.Query("order", string(models.Descending)))
+	if order != models.Descending && order != models.Ascending {
+		return nil, errors.New("Invalid order")
 	}
 
-	limit, err := strconv.Atoi(strLimit)
+	return &models.PageRequest{Cursor: c.Query("cursor"), Limit: limit, Order: order}, nil
+}
+
+// listError returns the response for an error of a list query.
+func listError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, database.ErrInvalidCursor) {
+		return c.Status(400).JSON(utils.JsonError("Invalid cursor"))
+	}
+
+	return c.Status(500).JSON(utils.JsonError(message))
+}
+
+func (s *QueryService) GetImagesHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error parsing limit"))
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
 	}
 
-	userID := c.Locals("user_id").(string)
-	images, err, hasMore := database.GetImages(userID, cursor, limit)
+	userID := middlewares.UserID(c)
+	images, nextCursor, err := database.GetImages(userID, page)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error getting images"))
+		return listError(c, err, "Error getting images")
 	}
 
 	return c.Status(200).JSON(fiber.Map{
-		"images":  images,
-		"hasMore": hasMore,
+		"images":     images,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
 	})
 }
 
 func (s *QueryService) GetImageHandler(c *fiber.Ctx) error {
-	imageID := c.Params("imageID")
-	image, err := database.GetImage(imageID)
+	image, err := authz.AuthorizeImage(middlewares.UserID(c), c.Params("imageID"), authz.Read)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error getting image"))
+		return notFoundError(c, err, "Image not found", "Error getting image")
 	}
 
 	return c.Status(200).JSON(image)
 }
 
+// GetImageByFolder returns a page of the images of a folder, or of the folder and all its descendants
+// if the recursive query parameter is true.
 func (s *QueryService) GetImageByFolder(c *fiber.Ctx) error {
-	folder := c.Params("folder")
-	cursor := c.Query("cursor")
-	strLimit := c.Query("limit")
-	if strLimit == "" {
-		strLimit = "10"
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
 	}
 
-	limit, err := strconv.Atoi(strLimit)
+	folder, err := authz.AuthorizeFolder(middlewares.UserID(c), c.Params("folderID"), authz.Read)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error parsing limit"))
+		return notFoundError(c, err, "Folder not found", "Error getting folder")
 	}
 
-	userID := c.Locals("user_id").(string)
+	getImages := database.GetImagesByFolder
+	if c.Query("recursive") == "true" {
+		getImages = database.GetImagesByFolderTree
+	}
 
-	images, hasMore, err := database.GetImagesByFolder(userID, folder, cursor, limit)
+	images, nextCursor, err := getImages(folder.UserID, folder.ID, page)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error getting image"))
+		return listError(c, err, "Error getting image")
 	}
 
 	return c.Status(200).JSON(fiber.Map{
-		"images":  images,
-		"hasMore": hasMore,
+		"images":     images,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
 	})
 }
 
 func (s *QueryService) GetFoldersHandler(c *fiber.Ctx) error {
-	cursor := c.Query("cursor")
-	strLimit := c.Query("limit")
-	if strLimit == "" {
-		strLimit = "10"
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
+	}
+
+	userID := middlewares.UserID(c)
+	folders, nextCursor, err := database.GetFolders(userID, page)
+	if err != nil {
+		return listError(c, err, "Error getting folders")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"folders":    folders,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
+
+// GetSubfoldersHandler returns a page of the folders in a folder, or of all its descendants
+// if the recursive query parameter is true.
+func (s *QueryService) GetSubfoldersHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
 	}
 
-	limit, err := strconv.Atoi(strLimit)
+	folder, err := authz.AuthorizeFolder(middlewares.UserID(c), c.Params("folderID"), authz.Read)
+	if err != nil {
+		return notFoundError(c, err, "Folder not found", "Error getting folder")
+	}
+
+	folders, nextCursor, err := database.GetSubfolders(folder.UserID, folder.ID, c.Query("recursive") == "true", page)
+	if err != nil {
+		return listError(c, err, "Error getting folders")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"folders":    folders,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
+
+// GetBreadcrumbsHandler returns the folders from the root to a folder, the folder is the last one.
+func (s *QueryService) GetBreadcrumbsHandler(c *fiber.Ctx) error {
+	folder, err := authz.AuthorizeFolder(middlewares.UserID(c), c.Params("folderID"), authz.Read)
+	if err != nil {
+		return notFoundError(c, err, "Folder not found", "Error getting folder")
+	}
+
+	breadcrumbs, err := database.GetFolderBreadcrumbs(folder.ID)
+	if err != nil {
+		return c.Status(500).JSON(utils.JsonError("Error getting breadcrumbs"))
+	}
+
+	return c.Status(200).JSON(fiber.Map{"breadcrumbs": breadcrumbs})
+}
+
+// GetTrashHandler returns a page of the images in the trash of the user.
+func (s *QueryService) GetTrashHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
+	}
+
+	userID := middlewares.UserID(c)
+	images, nextCursor, err := database.GetTrashedImages(userID, page)
+	if err != nil {
+		return listError(c, err, "Error getting trash")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"images":     images,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
+
+// GetTrashedFoldersHandler returns a page of the folders in the trash of the user.
+func (s *QueryService) GetTrashedFoldersHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error parsing limit"))
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
 	}
 
-	userID := c.Locals("user_id").(string)
-	folders, hasMore, err := database.GetFolders(userID, cursor, limit)
+	userID := middlewares.UserID(c)
+	folders, nextCursor, err := database.GetTrashedFolders(userID, page)
 	if err != nil {
-		return c.Status(500).JSON(utils.JsonError("Error getting folders"))
+		return listError(c, err, "Error getting trash")
 	}
 
 	return c.Status(200).JSON(fiber.Map{
-		"folders": folders,
-		"hasMore": hasMore,
+		"folders":    folders,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
 	})
 }
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log"
+
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// JWKSHandler returns the public keys that verify the access tokens, so other services can verify
+// the tokens without the private key.
+func (s *QueryService) JWKSHandler(c *fiber.Ctx) error {
+	jwks, err := utils.GetJWKS()
+	if err != nil {
+		log.Printf("Error loading jwt keys: %v", err)
+		return c.Status(500).JSON(utils.JsonError("Error getting keys"))
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(200).JSON(jwks)
+}
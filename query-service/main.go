@@ -2,7 +2,9 @@ package main
 
 import (
 	"log"
+	"strings"
 
+	"github.com/DarioRoman01/photos/bucket"
 	"github.com/DarioRoman01/photos/middlewares"
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,11 +17,27 @@ func main() {
 
 	app := fiber.New()
 
+	// the public routes are registered before the auth middleware, every other route requires a user
+	app.Get("/.well-known/jwks.json", svc.JWKSHandler)
+	app.Get("/shared/:token", svc.GetSharedHandler)
+	if prefix, ok := bucket.ServedFiles(); ok {
+		app.Get(strings.TrimSuffix(prefix, "/")+"/*", fileAuthMiddleware(), svc.GetFileHandler)
+	}
+
 	app.Use(middlewares.CheckAuthMiddleware())
 	app.Get("/images", svc.GetImagesHandler)
 	app.Get("/images/:imageID", svc.GetImageHandler)
 	app.Get("folders", svc.GetFoldersHandler)
 	app.Get("folders/:folderID", svc.GetImageByFolder)
+	app.Get("/folders/:folderID/folders", svc.GetSubfoldersHandler)
+	app.Get("/folders/:folderID/breadcrumbs", svc.GetBreadcrumbsHandler)
+	app.Get("/trash", svc.GetTrashHandler)
+	app.Get("/trash/folders", svc.GetTrashedFoldersHandler)
+	app.Get("/shares", svc.GetShareLinksHandler)
+	app.Get("/albums", svc.GetAlbumsHandler)
+	app.Get("/albums/:albumID", svc.GetAlbumHandler)
+	app.Get("/users/sessions", svc.GetSessionsHandler)
+	app.Get("/users/tokens", svc.GetPersonalAccessTokensHandler)
 
 	app.Listen(":3001")
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSessionsHandler returns the active sessions of the user, the session of the request is marked as current.
+func (s *QueryService) GetSessionsHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	sessions, err := database.GetSessions(userID)
+	if err != nil {
+		return c.Status(500).JSON(utils.JsonError("Error getting sessions"))
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == c.Locals("session_id")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"sessions": sessions})
+}
+
+// GetPersonalAccessTokensHandler returns the active personal access tokens of the user, without the secret tokens.
+func (s *QueryService) GetPersonalAccessTokensHandler(c *fiber.Ctx) error {
+	userID := middlewares.UserID(c)
+	tokens, err := database.GetPersonalAccessTokens(userID)
+	if err != nil {
+		return c.Status(500).JSON(utils.JsonError("Error getting tokens"))
+	}
+
+	return c.Status(200).JSON(fiber.Map{"tokens": tokens})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/DarioRoman01/photos/authz"
+	"github.com/DarioRoman01/photos/database"
+	"github.com/DarioRoman01/photos/middlewares"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// sharePasswordHeader is the header with the password of a protected share link.
+const sharePasswordHeader = "Share-Password"
+
+// GetShareLinksHandler returns a page of the share links of the user.
+func (s *QueryService) GetShareLinksHandler(c *fiber.Ctx) error {
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
+	}
+
+	userID := middlewares.UserID(c)
+	links, nextCursor, err := database.GetShareLinks(userID, page)
+	if err != nil {
+		return listError(c, err, "Error getting share links")
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"links":      links,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
+
+// GetSharedHandler resolves a share link to its images, it does not require authentication.
+// A view is counted every time the first page of the link is requested.
+func (s *QueryService) GetSharedHandler(c *fiber.Ctx) error {
+	link, err := database.GetShareLinkByToken(c.Params("token"))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return c.Status(404).JSON(utils.JsonError("Share link not found"))
+		}
+
+		return c.Status(500).JSON(utils.JsonError("Error getting share link"))
+	}
+
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt)) {
+		return c.Status(410).JSON(utils.JsonError("Share link expired"))
+	}
+
+	if link.HasPassword {
+		password := c.Get(sharePasswordHeader)
+		if password == "" {
+			return c.Status(401).JSON(utils.JsonError("Password required"))
+		}
+
+		if ok, _ := utils.ComparePasswords(password, link.Password); !ok {
+			return c.Status(401).JSON(utils.JsonError("Invalid password"))
+		}
+	}
+
+	page, err := parsePage(c)
+	if err != nil {
+		return c.Status(400).JSON(utils.JsonError(err.Error()))
+	}
+
+	images := []*models.Image{}
+	nextCursor := ""
+	if link.ImageID != nil {
+		image, err := database.GetImage(*link.ImageID)
+		if err == nil {
+			err = authz.Authorize(authz.Link(link), authz.Read, authz.Image(image))
+		}
+
+		if err != nil {
+			return c.Status(404).JSON(utils.JsonError("Image not found"))
+		}
+
+		images = append(images, image)
+	} else {
+		folder, err := database.GetFolder(*link.FolderID)
+		if err == nil {
+			err = authz.Authorize(authz.Link(link), authz.Read, authz.Folder(folder))
+		}
+
+		if err != nil {
+			return c.Status(404).JSON(utils.JsonError("Folder not found"))
+		}
+
+		images, nextCursor, err = database.GetImagesByFolder(folder.UserID, folder.ID, page)
+		if err != nil {
+			return listError(c, err, "Error getting images")
+		}
+	}
+
+	if page.Cursor == "" {
+		if err := database.IncrementShareLinkViews(link.ID); err != nil {
+			log.Printf("Error counting share link view: %v", err)
+		}
+	}
+
+	return c.Status(200).JSON(fiber.Map{
+		"images":     images,
+		"nextCursor": nextCursor,
+		"hasMore":    nextCursor != "",
+	})
+}
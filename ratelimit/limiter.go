@@ -0,0 +1,130 @@
+// Package ratelimit throttles the failed attempts of a key, like the logins of an account or an ip,
+// with an exponential backoff and a temporary lockout.
+package ratelimit
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Store keeps the failed attempts of the keys, it is shared by every limiter that uses it
+// so the keys must be prefixed by the caller.
+type Store interface {
+	// Attempt counts an attempt of the key unless the policy makes it wait after its failures in the window,
+	// and returns the wait. The wait is checked and the attempt counted atomically, so parallel attempts
+	// can not skip the wait.
+	Attempt(key string, policy Policy) (time.Duration, error)
+	// RemoveAttempt uncounts an attempt of the key that did not fail.
+	RemoveAttempt(key string) error
+	// Reset deletes the failures of the key.
+	Reset(key string) error
+}
+
+// NewStore returns the store selected by the RATE_LIMIT_STORE env variable, memory by default
+// or postgres to share the attempts between the replicas of a service.
+func NewStore() (Store, error) {
+	switch driver := os.Getenv("RATE_LIMIT_STORE"); driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(os.Getenv("POSTGRES_URL"))
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", driver)
+	}
+}
+
+// Policy defines how long a key must wait after its failures.
+type Policy struct {
+	FreeAttempts    int           // FreeAttempts is the number of failures allowed without waiting.
+	BaseDelay       time.Duration // BaseDelay is the wait after the first failure past the free attempts, it doubles with every failure.
+	MaxDelay        time.Duration // MaxDelay is the maximum wait of the backoff.
+	LockoutAttempts int           // LockoutAttempts is the number of failures that locks the key.
+	LockoutDuration time.Duration // LockoutDuration is the time a locked key must wait.
+	Window          time.Duration // Window is the time after the last failure the failures are forgotten.
+}
+
+var (
+	// AccountPolicy is the policy of the login attempts of an account.
+	AccountPolicy = Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: 10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+
+	// ResendPolicy is the policy of the verification mails sent again to an account, every mail is
+	// an attempt so an account can not be used to flood a mailbox.
+	ResendPolicy = Policy{
+		FreeAttempts:    2,
+		BaseDelay:       time.Minute,
+		MaxDelay:        time.Hour,
+		LockoutAttempts: 10,
+		LockoutDuration: 24 * time.Hour,
+		Window:          24 * time.Hour,
+	}
+
+	// IPPolicy is the policy of the login attempts from an ip, it allows more failures than
+	// AccountPolicy because many users can share an address.
+	IPPolicy = Policy{
+		FreeAttempts:    20,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAttempts: 100,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+)
+
+// delay returns the time a key must wait after its last failure.
+func (p Policy) delay(failures int) time.Duration {
+	if failures >= p.LockoutAttempts {
+		return p.LockoutDuration
+	}
+
+	if failures < p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay
+}
+
+// Limiter throttles the failed attempts of the keys with a policy.
+type Limiter struct {
+	store  Store  // store keeps the failures.
+	policy Policy // policy defines the waits.
+	prefix string // prefix is added to the keys, so limiters can share a store.
+}
+
+// NewLimiter returns a limiter that stores the failures of its keys with the given prefix.
+func NewLimiter(store Store, prefix string, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, prefix: prefix}
+}
+
+// Attempt counts an attempt of the key before it is verified and returns the time the key must wait before
+// trying, zero if it can try now. The attempt is only counted when the key does not have to wait, the attempts
+// that do not fail must be released or reset.
+func (l *Limiter) Attempt(key string) (time.Duration, error) {
+	return l.store.Attempt(l.prefix+key, l.policy)
+}
+
+// Release uncounts an attempt of the key that did not fail.
+func (l *Limiter) Release(key string) error {
+	return l.store.RemoveAttempt(l.prefix + key)
+}
+
+// Reset forgets the failures of the key after a successful attempt.
+func (l *Limiter) Reset(key string) error {
+	return l.store.Reset(l.prefix + key)
+}
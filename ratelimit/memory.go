@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryRetention is the time the failures are kept in memory after the last one.
+const memoryRetention = 24 * time.Hour
+
+// attempts are the failures of a key.
+type attempts struct {
+	failures    int       // failures is the number of failures in the window.
+	lastFailure time.Time // lastFailure is the time of the last failure.
+}
+
+// MemoryStore keeps the failures in memory, every replica of a service counts its own failures.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys map[string]*attempts
+}
+
+// NewMemoryStore returns a new MemoryStore, the old failures are removed every hour.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{keys: map[string]*attempts{}}
+	go s.removeExpired()
+	return s
+}
+
+func (s *MemoryStore) Attempt(key string, policy Policy) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.keys[key]
+	if !ok || time.Since(a.lastFailure) > policy.Window {
+		a = &attempts{}
+		s.keys[key] = a
+	}
+
+	if wait := policy.delay(a.failures) - time.Since(a.lastFailure); wait > 0 {
+		return wait, nil
+	}
+
+	a.failures++
+	a.lastFailure = time.Now()
+	return 0, nil
+}
+
+func (s *MemoryStore) RemoveAttempt(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.keys[key]; ok && a.failures > 0 {
+		a.failures--
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
+
+// removeExpired periodically deletes the keys without failures in the last memoryRetention.
+func (s *MemoryStore) removeExpired() {
+	for range time.Tick(time.Hour) {
+		s.mu.Lock()
+		for key, a := range s.keys {
+			if time.Since(a.lastFailure) > memoryRetention {
+				delete(s.keys, key)
+			}
+		}
+
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresStore keeps the failures in the login_attempts table, so they are shared by every replica.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore returns a new PostgresStore, the old failures are removed every hour.
+func NewPostgresStore(url string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	s := &PostgresStore{db: db}
+	go s.removeExpired()
+	return s, nil
+}
+
+func (s *PostgresStore) Attempt(key string, policy Policy) (time.Duration, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	// the row is created first so the attempts of the key wait for its lock, clock_timestamp is used
+	// instead of NOW so the time is not the start of a transaction that waited for the lock
+	_, err = tx.Exec(`
+		INSERT INTO login_attempts (key, failures, last_failure) VALUES ($1, 0, clock_timestamp())
+		ON CONFLICT (key) DO NOTHING
+	`, key)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var failures int
+	var elapsed float64
+	row := tx.QueryRow(
+		"SELECT failures, EXTRACT(EPOCH FROM clock_timestamp() - last_failure) FROM login_attempts WHERE key = $1 FOR UPDATE",
+		key,
+	)
+
+	if err := row.Scan(&failures, &elapsed); err != nil {
+		return 0, err
+	}
+
+	since := time.Duration(elapsed * float64(time.Second))
+	if since > policy.Window {
+		failures = 0
+	}
+
+	if wait := policy.delay(failures) - since; wait > 0 {
+		return wait, nil
+	}
+
+	_, err = tx.Exec(
+		"UPDATE login_attempts SET failures = $2, last_failure = clock_timestamp() WHERE key = $1",
+		key, failures+1,
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, tx.Commit()
+}
+
+func (s *PostgresStore) RemoveAttempt(key string) error {
+	_, err := s.db.Exec("UPDATE login_attempts SET failures = GREATEST(failures - 1, 0) WHERE key = $1", key)
+	return err
+}
+
+func (s *PostgresStore) Reset(key string) error {
+	_, err := s.db.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
+
+// removeExpired periodically deletes the keys without failures in the last day.
+func (s *PostgresStore) removeExpired() {
+	for range time.Tick(time.Hour) {
+		if _, err := s.db.Exec("DELETE FROM login_attempts WHERE last_failure < NOW() - INTERVAL '1 day'"); err != nil {
+			log.Printf("Error removing expired login attempts: %v", err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/DarioRoman01/photos/bucket"
 	"github.com/DarioRoman01/photos/uploadpb"
+	"github.com/DarioRoman01/photos/utils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -16,14 +17,19 @@ func main() {
 		log.Fatalf("Error listening: %s", err.Error())
 	}
 
-	s3Bucket, err := bucket.NewS3BucketRepository()
+	bucketRepo, err := bucket.NewBucketRepository()
 	if err != nil {
-		log.Fatalf("Error creating S3 bucket repository: %s", err.Error())
+		log.Fatalf("Error creating bucket repository: %s", err.Error())
 	}
 
-	bucket.SetBucketRepository(s3Bucket)
+	bucket.SetBucketRepository(bucketRepo)
 
-	server := NewServer()
+	maxSize, err := utils.UploadMaxSize()
+	if err != nil {
+		log.Fatalf("Error reading upload max size: %s", err.Error())
+	}
+
+	server := NewServer(maxSize)
 	grpcServer := grpc.NewServer()
 	uploadpb.RegisterUploadServiceServer(grpcServer, server)
 	reflection.Register(grpcServer)
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/DarioRoman01/photos/uploadpb"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// exifString returns the string value of the given exif field or an empty string if it is not present.
+func exifString(x *exif.Exif, field exif.FieldName) string {
+	tag, err := x.Get(field)
+	if err != nil {
+		return ""
+	}
+
+	val, err := tag.StringVal()
+	if err != nil {
+		return ""
+	}
+
+	return val
+}
+
+// exifInt returns the integer value of the given exif field or zero if it is not present.
+func exifInt(x *exif.Exif, field exif.FieldName) int32 {
+	tag, err := x.Get(field)
+	if err != nil {
+		return 0
+	}
+
+	val, err := tag.Int(0)
+	if err != nil {
+		return 0
+	}
+
+	return int32(val)
+}
+
+// exifRat returns the rational value of the given exif field as numerator and denominator.
+func exifRat(x *exif.Exif, field exif.FieldName) (int64, int64, bool) {
+	tag, err := x.Get(field)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	num, den, err := tag.Rat2(0)
+	if err != nil || den == 0 {
+		return 0, 0, false
+	}
+
+	return num, den, true
+}
+
+// extractMetadata reads the dimensions and the exif metadata of the given image,
+// fields that are not present in the file are left empty.
+func extractMetadata(file io.ReadSeeker) (*uploadpb.ImageMetadata, error) {
+	metadata := &uploadpb.ImageMetadata{}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if cfg, _, err := image.DecodeConfig(file); err == nil {
+		metadata.Width = int32(cfg.Width)
+		metadata.Height = int32(cfg.Height)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	x, err := exif.Decode(file)
+	if err != nil {
+		return metadata, nil
+	}
+
+	if takenAt, err := x.DateTime(); err == nil {
+		metadata.TakenAt = takenAt.Format(time.RFC3339)
+	}
+
+	metadata.CameraMake = exifString(x, exif.Make)
+	metadata.CameraModel = exifString(x, exif.Model)
+	metadata.Lens = exifString(x, exif.LensModel)
+	metadata.Iso = exifInt(x, exif.ISOSpeedRatings)
+	metadata.Orientation = exifInt(x, exif.Orientation)
+
+	if num, den, ok := exifRat(x, exif.ExposureTime); ok {
+		if num < den && num > 0 && den%num == 0 {
+			metadata.ExposureTime = fmt.Sprintf("1/%d", den/num)
+		} else {
+			metadata.ExposureTime = fmt.Sprintf("%g", float64(num)/float64(den))
+		}
+	}
+
+	if num, den, ok := exifRat(x, exif.FNumber); ok {
+		metadata.FNumber = float64(num) / float64(den)
+	}
+
+	if lat, long, err := x.LatLong(); err == nil {
+		metadata.HasLocation = true
+		metadata.Latitude = lat
+		metadata.Longitude = long
+	}
+
+	return metadata, nil
+}
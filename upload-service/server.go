@@ -1,8 +1,10 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"io"
+	"log"
+	"os"
 
 	"github.com/DarioRoman01/photos/bucket"
 	"github.com/DarioRoman01/photos/uploadpb"
@@ -10,48 +12,105 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// errTooLarge is returned when an upload exceeds the maximum object size.
+var errTooLarge = errors.New("file exceeds the maximum size")
+
 type Server struct {
 	uploadpb.UnimplementedUploadServiceServer
+	maxSize int64 // maxSize is the maximum size of an uploaded file in bytes.
 }
 
-func NewServer() *Server {
-	return &Server{}
+func NewServer(maxSize int64) *Server {
+	return &Server{maxSize: maxSize}
 }
 
-func (s *Server) Upload(stream uploadpb.UploadService_UploadServer) error {
-	buff := bytes.NewBuffer(nil)
-
-	filename := ""
-	username := ""
-	folder := ""
-	readed := false
-
+// receive writes the chunks of the stream to w until the client closes the stream,
+// the first request is the one already received by the caller.
+func (s *Server) receive(stream uploadpb.UploadService_UploadServer, req *uploadpb.UploadRequest, w io.Writer) error {
+	var size int64
 	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			location, err := bucket.Upload(buff, filename, username, folder)
-			if err != nil {
-				return status.Error(codes.Internal, "failed to upload image")
-			}
-
-			return stream.SendAndClose(&uploadpb.UploadResponse{
-				Location: location,
-			})
+		size += int64(len(req.Chunk))
+		if s.maxSize > 0 && size > s.maxSize {
+			return errTooLarge
 		}
 
-		if !readed {
-			filename = req.Filename
-			username = req.Username
-			folder = req.Folder
-			readed = true
+		if _, err := w.Write(req.Chunk); err != nil {
+			return err
 		}
 
-		if err != nil {
-			return status.Error(codes.Internal, "failed to process image")
+		var err error
+		req, err = stream.Recv()
+		if err == io.EOF {
+			return nil
 		}
 
-		if _, err := buff.Write(req.Chunk); err != nil {
-			return status.Error(codes.Internal, "failed to process image")
+		if err != nil {
+			return err
 		}
 	}
 }
+
+// Upload pipes the received chunks straight into the bucket, only a copy on disk is kept
+// to generate the variants and read the metadata once the file is stored.
+func (s *Server) Upload(stream uploadpb.UploadService_UploadServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "empty upload")
+	}
+
+	filename := req.Filename
+	username := req.Username
+	folder := req.Folder
+
+	spool, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		log.Printf("Error creating temporary file: %s", err)
+		return status.Error(codes.Internal, "failed to process image")
+	}
+
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	pr, pw := io.Pipe()
+	received := make(chan error, 1)
+	go func() {
+		err := s.receive(stream, req, pw)
+		pw.CloseWithError(err)
+		received <- err
+	}()
+
+	location, uploadErr := bucket.Upload(io.TeeReader(pr, spool), filename, username, folder)
+	// unblock the receiver if the bucket stopped reading before the end of the stream
+	pr.CloseWithError(io.ErrClosedPipe)
+	receiveErr := <-received
+	if errors.Is(receiveErr, errTooLarge) {
+		return status.Error(codes.ResourceExhausted, receiveErr.Error())
+	}
+
+	if uploadErr != nil {
+		log.Printf("Error uploading file: %s", uploadErr)
+		return status.Error(codes.Internal, "failed to upload image")
+	}
+
+	if receiveErr != nil {
+		return status.Error(codes.Internal, "failed to process image")
+	}
+
+	variants, err := generateVariants(spool, filename, username, folder)
+	if err != nil {
+		log.Printf("Error generating variants: %s", err)
+		return status.Error(codes.Internal, "failed to generate image variants")
+	}
+
+	metadata, err := extractMetadata(spool)
+	if err != nil {
+		log.Printf("Error reading metadata: %s", err)
+		return status.Error(codes.Internal, "failed to read image metadata")
+	}
+
+	return stream.SendAndClose(&uploadpb.UploadResponse{
+		Location: location,
+		Variants: variants,
+		Metadata: metadata,
+	})
+}
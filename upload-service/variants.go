@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/DarioRoman01/photos/bucket"
+	"github.com/DarioRoman01/photos/models"
+	"github.com/DarioRoman01/photos/utils"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// variant describes a resized copy of the uploaded images.
+type variant struct {
+	name    string // name is the name of the variant.
+	maxSize int    // maxSize is the maximum width and height of the variant in pixels.
+}
+
+// variants are the resized copies generated for every uploaded image.
+var variants = []variant{
+	{name: models.ThumbnailVariant, maxSize: 256},
+	{name: models.PreviewVariant, maxSize: 1600},
+}
+
+// resize scales the image to fit in a square of the given size keeping its aspect ratio,
+// images that already fit are not upscaled.
+func resize(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSize || height > maxSize {
+		if width > height {
+			width, height = maxSize, height*maxSize/width
+		} else {
+			width, height = width*maxSize/height, maxSize
+		}
+	}
+
+	if width < 1 {
+		width = 1
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	// jpeg has no transparency so the image is drawn over a white background
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+const (
+	// maxPixels is the maximum number of pixels of the images decoded to generate the variants,
+	// it bounds the memory used to decode a single image to about 200 MB.
+	maxPixels = 50_000_000
+	// maxDecodes is the maximum number of images decoded at the same time.
+	maxDecodes = 2
+)
+
+// decodeSlots bounds the images decoded at the same time, a slot is held from the decoding of an
+// image until its variants are encoded.
+var decodeSlots = make(chan struct{}, maxDecodes)
+
+// generateVariants decodes the given image and uploads its variants next to the original file,
+// it returns the location of every variant by its name. Files that are not images have no variants.
+func generateVariants(file io.ReadSeeker, filename, username, folder string) (map[string]string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil || cfg.Width*cfg.Height > maxPixels {
+		return nil, nil
+	}
+
+	encoded, err := encodeVariants(file, filename)
+	if encoded == nil || err != nil {
+		return nil, err
+	}
+
+	locations := make(map[string]string, len(variants))
+	for _, v := range variants {
+		location, err := bucket.Upload(encoded[v.name], utils.VariantFilename(filename, v.name), username, folder)
+		if err != nil {
+			return nil, err
+		}
+
+		locations[v.name] = location
+	}
+
+	return locations, nil
+}
+
+// encodeVariants decodes the given image and encodes its variants as jpeg, it waits for a decode slot
+// and returns nil if the image can not be decoded.
+func encodeVariants(file io.ReadSeeker, filename string) (map[string]*bytes.Buffer, error) {
+	decodeSlots <- struct{}{}
+	defer func() { <-decodeSlots }()
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		log.Printf("Error decoding image %s: %s", filename, err)
+		return nil, nil
+	}
+
+	encoded := make(map[string]*bytes.Buffer, len(variants))
+	for _, v := range variants {
+		buff := bytes.NewBuffer(nil)
+		if err := jpeg.Encode(buff, resize(img, v.maxSize), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+
+		encoded[v.name] = buff
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidFolderPath is returned when a folder path or name can not be used.
+var ErrInvalidFolderPath = errors.New("invalid folder path")
+
+// maxFolderNameLength is the maximum length of the name of a folder.
+const maxFolderNameLength = 255
+
+// ValidFolderName reports whether the name can be used as a folder name, the names are the
+// segments of the folder paths so they can not contain slashes.
+func ValidFolderName(name string) bool {
+	return name != "" && name != "." && name != ".." && len(name) <= maxFolderNameLength &&
+		!strings.ContainsAny(name, "/\\") && strings.TrimSpace(name) == name
+}
+
+// SplitFolderPath returns the names of the folders of a path like a/b/c, the empty segments
+// and the spaces around the names are ignored.
+func SplitFolderPath(path string) ([]string, error) {
+	names := []string{}
+	for _, name := range strings.Split(path, "/") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if !ValidFolderName(name) {
+			return nil, ErrInvalidFolderPath
+		}
+
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil, ErrInvalidFolderPath
+	}
+
+	return names, nil
+}
+
+// CleanFolderPath returns the path with the format stored in the folders, the default
+// folder is returned for an empty path.
+func CleanFolderPath(path string) (string, error) {
+	if strings.Trim(path, "/ ") == "" {
+		return "default", nil
+	}
+
+	names, err := SplitFolderPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(names, "/"), nil
+}
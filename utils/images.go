@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFilename is returned when the name of an uploaded file can not be used.
+var ErrInvalidFilename = errors.New("invalid file name")
+
+// maxFilenameLength is the maximum length of the name of an uploaded file.
+const maxFilenameLength = 255
+
+// ValidFilename reports whether the name can be used as the name of an uploaded file, the names are
+// the last segment of the object keys so they can not contain slashes or be a relative path.
+func ValidFilename(name string) bool {
+	return name != "" && name != "." && name != ".." && len(name) <= maxFilenameLength && !strings.ContainsAny(name, "/\\")
+}
+
+// ImageObjectName returns the name of the file where an image is stored, the id of the image is part of the name
+// so the files of the images with the same name in the same folder, in the trash or not, do not replace each other.
+func ImageObjectName(id, filename string) string {
+	return id + "-" + filename
+}
+
+// VariantFilename returns the name of the file where the given variant of an image is stored.
+// Variants are always encoded as jpeg, so "photo.png" becomes "photo_thumbnail.jpg".
+func VariantFilename(filename, variant string) string {
+	base := strings.TrimSuffix(filename, path.Ext(filename))
+	return fmt.Sprintf("%s_%s.jpg", base, variant)
+}
+
+// defaultUploadMaxSize is the maximum size of an uploaded file when UPLOAD_MAX_SIZE is not set.
+const defaultUploadMaxSize = 100 << 20
+
+// UploadMaxSize returns the maximum size of an uploaded file in bytes from the UPLOAD_MAX_SIZE env variable,
+// the upload service enforces it and the command service rejects the larger resumable uploads when they are created.
+func UploadMaxSize() (int64, error) {
+	env := os.Getenv("UPLOAD_MAX_SIZE")
+	if env == "" {
+		return defaultUploadMaxSize, nil
+	}
+
+	size, err := strconv.ParseInt(env, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid UPLOAD_MAX_SIZE: %w", err)
+	}
+
+	return size, nil
+}
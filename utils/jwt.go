@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -9,6 +13,47 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+const (
+	// OIDCStateTokenTTL is the time the user has to login in the identity provider.
+	OIDCStateTokenTTL = 10 * time.Minute
+	// TwoFactorTokenTTL is the time the user has to send the TOTP code after the password.
+	TwoFactorTokenTTL = 5 * time.Minute
+	// AccessTokenTTL is the lifetime of an access token, it is renewed with the refresh token of its session.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is the lifetime of a refresh token, every refresh issues a new one.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// CreateAccessToken creates a short lived access token for the given session.
+func CreateAccessToken(username, id, sessionID string) (string, error) {
+	claims := models.Claims{
+		Username:  username,
+		UserID:    id,
+		Type:      models.AccessToken.String(),
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// CreateTwoFactorToken creates the intermediate token of a login that requires a TOTP code,
+// it can only be exchanged for an access token with a valid code.
+func CreateTwoFactorToken(username, id string) (string, error) {
+	claims := models.Claims{
+		Username: username,
+		UserID:   id,
+		Type:     models.TwoFactorToken.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TwoFactorTokenTTL)),
+		},
+	}
+
+	return signToken(claims)
+}
+
 func CreateToken(username, id, tipe string) (string, error) {
 	claims := models.Claims{
 		Username: username,
@@ -19,15 +64,93 @@ func CreateToken(username, id, tipe string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return signToken(claims)
+}
+
+// passwordResetSecret returns the PASSWORD_RESET_SECRET that keys the fingerprints of the passwords, it is required
+// even with asymmetric keys so the fingerprint of a password hash can not be computed without it.
+func passwordResetSecret() ([]byte, error) {
+	secret := []byte(os.Getenv("PASSWORD_RESET_SECRET"))
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("PASSWORD_RESET_SECRET must have at least %d bytes", minSecretLength)
+	}
+
+	return secret, nil
+}
+
+// CheckPasswordResetSecret returns an error if the PASSWORD_RESET_SECRET is missing, the services that reset
+// the passwords call it on startup.
+func CheckPasswordResetSecret() error {
+	_, err := passwordResetSecret()
+	return err
+}
+
+// PasswordFingerprint returns a keyed digest of the given password hash, it changes every time the password changes.
+func PasswordFingerprint(passwordHash string) (string, error) {
+	secret, err := passwordResetSecret()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(passwordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CreatePasswordResetToken creates a change password token that expires in one hour, the token id is the
+// fingerprint of the current password hash so the token is rejected once the password is changed.
+func CreatePasswordResetToken(username, id, passwordHash string) (string, error) {
+	fingerprint, err := PasswordFingerprint(passwordHash)
+	if err != nil {
+		return "", err
+	}
+
+	claims := models.Claims{
+		Username: username,
+		UserID:   id,
+		Type:     models.ChangePasswordToken.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        fingerprint,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// CreateOIDCStateToken creates the token that keeps the state of a login with an identity provider.
+func CreateOIDCStateToken(state, nonce, verifier string) (string, error) {
+	claims := models.OIDCStateClaims{
+		Type:     models.OIDCStateToken.String(),
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(OIDCStateTokenTTL)),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// VerifyOIDCStateToken verifies a token created with CreateOIDCStateToken.
+func VerifyOIDCStateToken(tokenString string) (*models.OIDCStateClaims, error) {
+	claims := new(models.OIDCStateClaims)
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || claims.Type != models.OIDCStateToken.String() {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
 }
 
 func VerifyToken(tokenString string) (*models.Claims, error) {
 	claims := new(models.Claims)
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey)
 
 	if err != nil {
 		return nil, err
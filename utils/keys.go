@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// keysReloadInterval is the time the keys are cached before the files are read again,
+// so rotated keys are picked up without restarting the services.
+const keysReloadInterval = time.Minute
+
+// minSecretLength is the minimum length of the JWT_SECRET of the HS256 tokens.
+const minSecretLength = 32
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`           // Kty is the key type, OKP or RSA.
+	Kid string `json:"kid"`           // Kid is the id of the key, sent in the kid header of the tokens.
+	Use string `json:"use"`           // Use is the usage of the key, always sig.
+	Alg string `json:"alg"`           // Alg is the algorithm of the tokens signed with the key.
+	Crv string `json:"crv,omitempty"` // Crv is the curve of an OKP key.
+	X   string `json:"x,omitempty"`   // X is the public key of an OKP key.
+	N   string `json:"n,omitempty"`   // N is the modulus of an RSA key.
+	E   string `json:"e,omitempty"`   // E is the exponent of an RSA key.
+}
+
+// JWKS is a set of public keys in the JSON Web Key format.
+type JWKS struct {
+	Keys []JWK `json:"keys"` // Keys are the public keys that verify the tokens.
+}
+
+// keySet holds the key that signs the tokens and the keys that verify them. The signing key is read from
+// the JWT_SIGNING_KEY file and the verification keys from the pem files in the JWT_VERIFY_KEYS directory,
+// the public key of the signing key is always a verification key. When none is configured the tokens are
+// signed with the JWT_SECRET using HS256, only if JWT_ALLOW_HMAC is true.
+type keySet struct {
+	mu         sync.RWMutex
+	loadedAt   time.Time                   // loadedAt is the time the files were read.
+	signingKey crypto.Signer               // signingKey is the private key that signs the tokens, nil if only verifying.
+	signingKid string                      // signingKid is the id of the signing key.
+	verifyKeys map[string]crypto.PublicKey // verifyKeys are the public keys that verify the tokens by id.
+	secret     []byte                      // secret is the JWT_SECRET of the HS256 tokens, nil if the keys are asymmetric.
+}
+
+var keys = &keySet{}
+
+// keyID returns the id of a public key, the base64 encoded sha256 of its DER encoding.
+func keyID(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+// parsePEMKey parses a pem encoded private or public key, private keys are returned as a crypto.Signer.
+func parsePEMKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block %s", block.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey, *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PublicKey, *rsa.PublicKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, only Ed25519 and RSA keys are supported", key)
+	}
+}
+
+// publicKey returns the public key of a parsed key.
+func publicKey(key interface{}) crypto.PublicKey {
+	if signer, ok := key.(crypto.Signer); ok {
+		return signer.Public()
+	}
+
+	return key
+}
+
+// load reads the key files, the keys are replaced only if every file is valid.
+func (s *keySet) load() error {
+	var signingKey crypto.Signer
+	var signingKid string
+	verifyKeys := map[string]crypto.PublicKey{}
+
+	if path := os.Getenv("JWT_SIGNING_KEY"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		key, err := parsePEMKey(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("%s: JWT_SIGNING_KEY must be a private key", path)
+		}
+
+		signingKid, err = keyID(signer.Public())
+		if err != nil {
+			return err
+		}
+
+		signingKey = signer
+		verifyKeys[signingKid] = signer.Public()
+	}
+
+	if dir := os.Getenv("JWT_VERIFY_KEYS"); dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			key, err := parsePEMKey(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+
+			kid, err := keyID(publicKey(key))
+			if err != nil {
+				return err
+			}
+
+			verifyKeys[kid] = publicKey(key)
+		}
+	}
+
+	// the tokens signed with an empty or short secret could be forged, so the hmac mode must be enabled explicitly
+	var secret []byte
+	if signingKey == nil && len(verifyKeys) == 0 {
+		if os.Getenv("JWT_ALLOW_HMAC") != "true" {
+			return errors.New("JWT_SIGNING_KEY or JWT_VERIFY_KEYS is required, set JWT_ALLOW_HMAC=true to use the JWT_SECRET")
+		}
+
+		secret = []byte(os.Getenv("JWT_SECRET"))
+		if len(secret) < minSecretLength {
+			return fmt.Errorf("JWT_SECRET must have at least %d bytes", minSecretLength)
+		}
+	}
+
+	s.signingKey = signingKey
+	s.signingKid = signingKid
+	s.verifyKeys = verifyKeys
+	s.secret = secret
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// refresh reloads the key files when the cached keys are older than keysReloadInterval, if the files
+// can not be read the previous keys are kept. An error is returned only if the keys were never loaded.
+func (s *keySet) refresh() error {
+	s.mu.RLock()
+	fresh := !s.loadedAt.IsZero() && time.Since(s.loadedAt) < keysReloadInterval
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < keysReloadInterval {
+		return nil
+	}
+
+	if err := s.load(); err != nil {
+		if s.loadedAt.IsZero() {
+			return err
+		}
+
+		log.Printf("Error reloading jwt keys, using the previous keys: %v", err)
+		s.loadedAt = time.Now()
+	}
+
+	return nil
+}
+
+// LoadKeys reads the keys that sign and verify the tokens, the services call it on startup so they do not
+// start without valid keys.
+func LoadKeys() error {
+	return keys.refresh()
+}
+
+// asymmetric checks if the tokens are signed and verified with public keys.
+func (s *keySet) asymmetric() bool {
+	return s.signingKey != nil || len(s.verifyKeys) > 0
+}
+
+// signingMethod returns the jwt signing method of the given key.
+func signingMethod(key interface{}) jwt.SigningMethod {
+	switch key.(type) {
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodRS256
+	}
+}
+
+// signToken signs the claims with the signing key, or with the JWT_SECRET when the hmac mode is enabled.
+func signToken(claims jwt.Claims) (string, error) {
+	if err := keys.refresh(); err != nil {
+		return "", err
+	}
+
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	if keys.signingKey == nil {
+		if keys.asymmetric() {
+			return "", errors.New("JWT_SIGNING_KEY is required to create tokens")
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(keys.secret)
+	}
+
+	token := jwt.NewWithClaims(signingMethod(keys.signingKey), claims)
+	token.Header["kid"] = keys.signingKid
+	return token.SignedString(keys.signingKey)
+}
+
+// verificationKey returns the key that verifies the given token, the algorithm of the token must be
+// the one of the key so a public key is never used as an HMAC secret.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if err := keys.refresh(); err != nil {
+		return nil, err
+	}
+
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	if !keys.asymmetric() {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return keys.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := keys.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if signingMethod(key).Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return key, nil
+}
+
+// GetJWKS returns the public keys that verify the tokens, it is empty in the hmac mode.
+func GetJWKS() (*JWKS, error) {
+	if err := keys.refresh(); err != nil {
+		return nil, err
+	}
+
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	jwks := &JWKS{Keys: []JWK{}}
+	for kid, key := range keys.verifyKeys {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: signingMethod(key).Alg()}
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks, nil
+}
@@ -0,0 +1,24 @@
+package utils
+
+import "strings"
+
+// maxUsernameLength is the maximum length of a username.
+const maxUsernameLength = 255
+
+// ValidUsername reports whether the name can be used as a username, the usernames are the first
+// segment of the keys of the objects of the user so they can not contain slashes.
+func ValidUsername(name string) bool {
+	return name != "" && name != "." && name != ".." && len(name) <= maxUsernameLength &&
+		!strings.ContainsAny(name, "/\\") && strings.TrimSpace(name) == name
+}
+
+// SanitizeUsername returns the name with the slashes replaced by dashes and without the spaces around it,
+// shortened so a suffix of the given length can be added. The result must still be checked with ValidUsername.
+func SanitizeUsername(name string, suffixLength int) string {
+	name = strings.NewReplacer("/", "-", "\\", "-").Replace(name)
+	if max := maxUsernameLength - suffixLength; len(name) > max {
+		name = strings.ToValidUTF8(name[:max], "")
+	}
+
+	return strings.TrimSpace(name)
+}
@@ -2,9 +2,13 @@ package utils
 
 import "strings"
 
-func ChangeUrlPath(url string, path string) string {
-	splitUrl := strings.Split(url, "/")
-	splitUrl[4] = path
-	newurl := strings.Join(splitUrl, "/")
-	return newurl
+// ChangeUrlPath returns the url of a file moved from the folder with the path oldPath to newPath,
+// the folder path is the part of the url between the username and the file name.
+func ChangeUrlPath(url, oldPath, newPath string) string {
+	i := strings.LastIndex(url, "/")
+	if i < 0 || !strings.HasSuffix(url[:i], "/"+oldPath) {
+		return url
+	}
+
+	return strings.TrimSuffix(url[:i], oldPath) + newPath + url[i:]
 }